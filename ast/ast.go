@@ -13,13 +13,101 @@ type Program = Block
 type Parser struct {
 	tokens []tokens.Token
 	index  int
-	file string
+	file   string
+	errors ErrorList
+}
+
+// ErrorList holds every syntax error of a parse in source order.
+type ErrorList []*tokens.SyntaxError
+
+func (E ErrorList) Error() string {
+	switch len(E) {
+	case 0:
+		return "no errors"
+	case 1:
+		return E[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", E[0], len(E)-1)
+}
+
+// Unwrap is the first error.
+func (E ErrorList) Unwrap() error {
+	if len(E) == 0 {
+		return nil
+	}
+	return E[0]
 }
 
 func (P *Parser) Meta(t tokens.Token) *Meta {
 	return NewMeta(t, P.file)
 }
 
+// MetaFrom spans from start up to the last consumed token.
+func (P *Parser) MetaFrom(t tokens.Token, start tokens.Position) *Meta {
+	meta := P.Meta(t)
+	meta.Range = tokens.Span{Start: start, End: P.end()}
+	return meta
+}
+
+func (P *Parser) end() tokens.Position {
+	if P.index == 0 {
+		return tokens.Position{}
+	}
+	return P.tokens[P.index-1].End
+}
+
+func (P *Parser) error(code string, message string, at tokens.Span) error {
+	return &tokens.SyntaxError{Code: code, Message: message, File: P.file, Span: at}
+}
+
+// eof is the empty span after the last token.
+func (P *Parser) eof() tokens.Span {
+	end := P.end()
+	return tokens.Span{Start: end, End: end}
+}
+
+// recover records err and skips the rest of the broken statement
+// which began at start, the statement is replaced by an error node.
+func (P *Parser) recover(err error, start int) Node {
+	syntax, ok := err.(*tokens.SyntaxError)
+	if !ok {
+		syntax = &tokens.SyntaxError{Message: err.Error(), File: P.file, Span: P.eof()}
+	}
+	P.errors = append(P.errors, syntax)
+	P.synchronize(syntax.Span.Start.Line)
+	if P.index == start {
+		P.next()
+	}
+	first := P.tokens[start]
+	return ErrorNode{syntax, P.MetaFrom(first, first.Start)}
+}
+
+// synchronize skips tokens up to the next line or the } closing
+// the current block, brackets opened on the way are skipped whole.
+func (P *Parser) synchronize(line int) {
+	depth := 0
+	for peek, peeked := P.peek(); peeked; peek, peeked = P.peek() {
+		if depth == 0 && peek.Start.Line > line {
+			return
+		}
+		switch peek.Type {
+		case tokens.ScopeOpen, tokens.ParenOpen:
+			depth++
+		case tokens.ParenClosed:
+			if depth > 0 {
+				depth--
+			}
+		case tokens.ScopeClosed:
+			if depth == 0 {
+				return
+			}
+			depth--
+		}
+		line = peek.End.Line
+		P.next()
+	}
+}
+
 func Parsep(lexed []tokens.Token) Node {
 	val, err := Parse(lexed, "constant.go")
 	if err != nil {
@@ -28,12 +116,21 @@ func Parsep(lexed []tokens.Token) Node {
 	return val
 }
 
+// Parse parses all of lexed, on syntax errors the partial tree
+// is returned with an ErrorList of every error.
 func Parse(lexed []tokens.Token, file string) (Node, error) {
 	parser := Parser{
 		tokens: lexed,
-		file: file,
+		file:   file,
+	}
+	node, err := parser.parse()
+	if err != nil {
+		return nil, err
 	}
-	return parser.parse()
+	if len(parser.errors) > 0 {
+		return node, parser.errors
+	}
+	return node, nil
 }
 
 func (P *Parser) parse() (Node, error) {
@@ -41,23 +138,27 @@ func (P *Parser) parse() (Node, error) {
 	body := make([]Node, l)
 	bindex := 0
 	peek, peeked := P.peek()
+	start := peek.Start
 	returnOnScopeClose := peeked && peek.Type == tokens.ScopeOpen
 
 	if returnOnScopeClose {
 		P.next()
 	}
 
+	closed := !returnOnScopeClose
 	for P.index < len(P.tokens) {
 		if returnOnScopeClose {
-			peek, peeked = P.peek()
+			peek, peeked := P.peek()
 			if peeked && peek.Type == tokens.ScopeClosed {
 				P.next()
+				closed = true
 				break
 			}
 		}
+		statement := P.index
 		node, err := P.pullValue()
 		if err != nil {
-			return nil, err
+			node = P.recover(err, statement)
 		}
 		body[bindex] = node
 		bindex++
@@ -68,9 +169,105 @@ func (P *Parser) parse() (Node, error) {
 			copy(body, old)
 		}
 	}
-	return Block{body[0:bindex], P.Meta(peek)}, nil
+	if !closed {
+		P.errors = append(P.errors, &tokens.SyntaxError{
+			Code: "E106", Message: "Unclosed scope", File: P.file, Span: peek.Span()})
+	}
+	return Block{body[0:bindex], P.MetaFrom(peek, start)}, nil
+}
+
+// precedence of the binary operators, higher binds tighter.
+// All binary operators are left associative, assignment binds
+// loosest and is right associative. The unary operators !, ~ and -
+// bind tighter than any binary operator.
+//
+//	5  *  /  %  <<  >>  &
+//	4  +  -  |  ^
+//	3  ==  <  <=  >  >=
+//	2  &&
+//	1  ||
+var precedence = map[tokens.Operator]int{
+	tokens.OperatorMul:  5,
+	tokens.OperatorDiv:  5,
+	tokens.OperatorMod:  5,
+	tokens.OperatorLsh:  5,
+	tokens.OperatorRsh:  5,
+	tokens.OperatorAnd:  5,
+	tokens.OperatorAdd:  4,
+	tokens.OperatorSub:  4,
+	tokens.OperatorOr:   4,
+	tokens.OperatorXor:  4,
+	tokens.OperatorEq:   3,
+	tokens.OperatorLt:   3,
+	tokens.OperatorLe:   3,
+	tokens.OperatorGt:   3,
+	tokens.OperatorGe:   3,
+	tokens.OperatorLand: 2,
+	tokens.OperatorLor:  1,
+}
+
+// unaryOperators maps the prefix operators to their operation.
+var unaryOperators = map[tokens.Operator]tokens.Operator{
+	tokens.OperatorNot: tokens.OperatorNot,
+	tokens.OperatorSub: tokens.OperatorNeg,
+}
+
+// expression parses a value followed by binary operators which bind
+// tighter than min, an assignment is only parsed at the lowest level.
+func (P *Parser) expression(min int) (Node, error) {
+	left, err := P.unary()
+	if err != nil {
+		return nil, err
+	}
+	for peek, peeked := P.peek(); peeked; peek, peeked = P.peek() {
+		if peek.Type == tokens.Assignment && min == 0 {
+			P.next()
+			value, err := P.expression(0)
+			if err != nil {
+				return nil, err
+			}
+			return Assignment{left, value, P.MetaFrom(peek, left.Span().Start)}, nil
+		}
+		if peek.Type != tokens.OperatorType {
+			break
+		}
+		prec, ok := precedence[peek.ValueInt]
+		if !ok || prec <= min {
+			break
+		}
+		P.next()
+		right, err := P.expression(prec)
+		if err != nil {
+			return nil, err
+		}
+		left = BinaryExpression{peek.ValueInt, left, right, P.MetaFrom(peek, left.Span().Start)}
+	}
+	return left, nil
 }
 
+// unary parses prefix operators applied to a value and its appendages.
+func (P *Parser) unary() (Node, error) {
+	next, has := P.peek()
+	if !has || next.Type != tokens.OperatorType {
+		v, err := P._pullValue()
+		if err != nil {
+			return nil, err
+		}
+		return P.checkAppendage(v)
+	}
+	operation, ok := unaryOperators[next.ValueInt]
+	if !ok {
+		return nil, P.error("E107", "Unexpected operator "+next.Content, next.Span())
+	}
+	P.next()
+	val, err := P.unary()
+	if err != nil {
+		return nil, err
+	}
+	return UnaryExpression{operation, val, P.MetaFrom(next, next.Start)}, nil
+}
+
+// checkAppendage parses member selections, calls and indexes following prev.
 func (P *Parser) checkAppendage(prev Node) (Node, error) {
 	peek, peeked := P.peek()
 	if !peeked {
@@ -83,35 +280,28 @@ func (P *Parser) checkAppendage(prev Node) (Node, error) {
 		if err != nil {
 			return nil, err
 		}
-		return P.checkAppendage(MemberSelector{prev, val, P.Meta(peek)})
+		return P.checkAppendage(MemberSelector{prev, val, P.MetaFrom(peek, prev.Span().Start)})
 	case tokens.ParenOpen:
 		P.next()
-		args, err := P.argList(false)
+		args, err := P.argList(tokens.ParenClosed)
 		if err != nil {
 			return nil, err
 		}
-		return P.checkAppendage(Expression{prev, args, P.Meta(peek)})
-	case tokens.Assignment:
-		P.next()
-		node, err := P.pullValue()
-		if err != nil {
-			return nil, err
+		return P.checkAppendage(Expression{prev, args, P.MetaFrom(peek, prev.Span().Start)})
+	case tokens.BracketOpen:
+		// A [ on the next line starts a list literal, not an index
+		if P.index == 0 || P.tokens[P.index-1].End.Line != peek.Start.Line {
+			return prev, nil
 		}
-		return Assignment{prev, node, P.Meta(peek)}, nil
-	case tokens.OperatorType:
 		P.next()
-		node, err := P.pullValue()
+		index, err := P.pullValue()
 		if err != nil {
 			return nil, err
 		}
-		if bin, ok := node.(BinaryExpression); ok {
-			if bin.Operation > peek.ValueInt {
-				expr := BinaryExpression{peek.ValueInt, prev, bin.Left, P.Meta(peek)}
-				bin.Left = expr
-				return bin, nil
-			}
+		if _, err := P.expect(tokens.BracketClosed, "E108", "Expected ]"); err != nil {
+			return nil, err
 		}
-		return BinaryExpression{peek.ValueInt, prev, node, P.Meta(peek)}, nil
+		return P.checkAppendage(Index{prev, index, P.MetaFrom(peek, prev.Span().Start)})
 	}
 	return prev, nil
 }
@@ -131,22 +321,26 @@ func (P *Parser) next() (tokens.Token, bool) {
 	return tokens.Token{}, false
 }
 
-func (P *Parser) argList(identifierOnly bool) ([]Node, error) {
+// closers names the tokens which close a list of values.
+var closers = map[tokens.TokenType]string{
+	tokens.ParenClosed:   ")",
+	tokens.BracketClosed: "]",
+}
+
+// argList parses comma separated values up to and including closing.
+func (P *Parser) argList(closing tokens.TokenType) ([]Node, error) {
 	args := make([]Node, 0)
 	requiresComma := false
 
-	for peek, peeked := P.peek(); peeked && peek.Type != tokens.ParenClosed; peek, peeked = P.peek() {
+	for peek, peeked := P.peek(); peeked && peek.Type != closing; peek, peeked = P.peek() {
 		if requiresComma && peek.Type == tokens.Comma {
 			requiresComma = false
 			P.next()
 			continue
 		} else if requiresComma {
-			if peek.Type == tokens.ParenClosed {
-				return args, nil
-			}
-			return nil, fmt.Errorf("expected comma line %d", peek.Line)
+			return nil, P.error("E101", "Expected comma", peek.Span())
 		} else if peek.Type == tokens.Comma {
-			return nil, fmt.Errorf("Unexpected comma line %d", peek.Line)
+			return nil, P.error("E102", "Unexpected comma", peek.Span())
 		}
 		arg, err := P.pullValue()
 		requiresComma = true
@@ -155,34 +349,38 @@ func (P *Parser) argList(identifierOnly bool) ([]Node, error) {
 		}
 		args = append(args, arg)
 	}
-	P.next()
+	if _, has := P.next(); !has {
+		return nil, P.error("E108", "Expected "+closers[closing], P.eof())
+	}
 	return args, nil
 }
 
 func (P *Parser) pullValue() (Node, error) {
-	v, err := P._pullValue()
-	if err != nil {
-		return nil, err
-	}
-	return P.checkAppendage(v)
+	return P.expression(0)
 }
 
 func (P *Parser) _pullValue() (Node, error) {
 	next, has := P.peek()
 	if !has {
-		return nil, fmt.Errorf("Expected value")
+		return nil, P.error("E103", "Expected value", P.eof())
 	}
 
 	switch next.Type {
 	case tokens.ScopeOpen:
+		if P.isDictLiteral() {
+			return P.dictLiteral()
+		}
 		b, err := P.parse()
 		if err != nil {
 			return nil, err
 		}
-		return Scope{b, P.Meta(next)}, nil
+		return Scope{b, P.MetaFrom(next, next.Start)}, nil
 	case tokens.ParenOpen:
+		if !P.isFunctionDefinition() {
+			return P.group()
+		}
 		P.next()
-		args, err := P.argList(true)
+		args, err := P.argList(tokens.ParenClosed)
 		if err != nil {
 			return nil, err
 		}
@@ -190,7 +388,7 @@ func (P *Parser) _pullValue() (Node, error) {
 		for i := 0; i < len(args); i++ {
 			arg, ok := args[i].(Identifier)
 			if !ok {
-				return nil, fmt.Errorf("Identifier expected line: %d", next.Line)
+				return nil, P.error("E104", "Identifier expected as parameter", args[i].Span())
 			}
 			arglist[i] = arg
 		}
@@ -198,7 +396,18 @@ func (P *Parser) _pullValue() (Node, error) {
 		if err != nil {
 			return nil, err
 		}
-		return FunctionDefinition{b, arglist, P.Meta(next)}, nil
+		return FunctionDefinition{b, arglist, P.MetaFrom(next, next.Start)}, nil
+	case tokens.Hash:
+		return P.mapLiteral()
+	case tokens.Scoper:
+		return P.scopedIdentifier()
+	case tokens.BracketOpen:
+		P.next()
+		values, err := P.argList(tokens.BracketClosed)
+		if err != nil {
+			return nil, err
+		}
+		return ListLiteral{values, P.MetaFrom(next, next.Start)}, nil
 	case tokens.Identifier:
 		P.next()
 		identifier, err := P.parseIdentifier(next)
@@ -218,15 +427,154 @@ func (P *Parser) _pullValue() (Node, error) {
 	case tokens.Boolean:
 		P.next()
 		return Bool{next.ValueInt == 1, P.Meta(next)}, nil
-	case tokens.OperatorType:
+	}
+	return nil, P.error("E105", "Identifier Expected", next.Span())
+}
+
+// scopedIdentifier parses the @name or @@name target of an assignment.
+func (P *Parser) scopedIdentifier() (Node, error) {
+	scoper, _ := P.next()
+	module := false
+	if peek, peeked := P.peek(); peeked && peek.Type == tokens.Scoper {
 		P.next()
-		val, err := P.pullValue()
+		module = true
+	}
+	name, err := P.expect(tokens.Identifier, "E111", "Identifier expected after @")
+	if err != nil {
+		return nil, err
+	}
+	if peek, peeked := P.peek(); !peeked || peek.Type != tokens.Assignment {
+		return nil, P.error("E111", "@"+name.Content+" can only be assigned", P.MetaFrom(scoper, scoper.Start).Range)
+	}
+	return ScopedIdentifier{name.Content, module, P.MetaFrom(scoper, scoper.Start)}, nil
+}
+
+// isDictLiteral reports whether the { at the current token opens
+// a dict literal, blocks can't start with a dot.
+func (P *Parser) isDictLiteral() bool {
+	return P.index+1 < len(P.tokens) && P.tokens[P.index+1].Type == tokens.Dot
+}
+
+// entries parses comma separated entries up to the closing },
+// a trailing comma is allowed.
+func (P *Parser) entries(entry func() (Entry, error)) ([]Entry, error) {
+	entries := make([]Entry, 0)
+	for {
+		peek, peeked := P.peek()
+		if !peeked {
+			return nil, P.error("E108", "Expected }", P.eof())
+		}
+		if peek.Type == tokens.ScopeClosed {
+			P.next()
+			return entries, nil
+		}
+		e, err := entry()
 		if err != nil {
 			return nil, err
 		}
-		return UnaryExpression{next.ValueInt, val, P.Meta(next)}, nil
+		entries = append(entries, e)
+		peek, peeked = P.peek()
+		if peeked && peek.Type == tokens.Comma {
+			P.next()
+		} else if peeked && peek.Type != tokens.ScopeClosed {
+			return nil, P.error("E101", "Expected comma", peek.Span())
+		}
+	}
+}
+
+// expect consumes the next token if it is of type t.
+func (P *Parser) expect(t tokens.TokenType, code string, message string) (tokens.Token, error) {
+	next, has := P.peek()
+	if !has {
+		return next, P.error(code, message, P.eof())
+	}
+	if next.Type != t {
+		return next, P.error(code, message, next.Span())
+	}
+	P.next()
+	return next, nil
+}
+
+// mapLiteral parses #{key: value, ...}.
+func (P *Parser) mapLiteral() (Node, error) {
+	hash, _ := P.next()
+	if _, err := P.expect(tokens.ScopeOpen, "E109", "Expected { after #"); err != nil {
+		return nil, err
+	}
+	entries, err := P.entries(func() (Entry, error) {
+		key, err := P.pullValue()
+		if err != nil {
+			return Entry{}, err
+		}
+		if _, err := P.expect(tokens.Colon, "E109", "Expected :"); err != nil {
+			return Entry{}, err
+		}
+		value, err := P.pullValue()
+		return Entry{key, value}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return MapLiteral{entries, P.MetaFrom(hash, hash.Start)}, nil
+}
+
+// dictLiteral parses {.name = value, ...}.
+func (P *Parser) dictLiteral() (Node, error) {
+	open, _ := P.next()
+	entries, err := P.entries(func() (Entry, error) {
+		if _, err := P.expect(tokens.Dot, "E110", "Expected .name"); err != nil {
+			return Entry{}, err
+		}
+		name, err := P.expect(tokens.Identifier, "E110", "Expected .name")
+		if err != nil {
+			return Entry{}, err
+		}
+		if _, err := P.expect(tokens.Assignment, "E110", "Expected = after ."+name.Content); err != nil {
+			return Entry{}, err
+		}
+		value, err := P.pullValue()
+		return Entry{Identifier{name.Content, P.Meta(name)}, value}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return DictLiteral{entries, P.MetaFrom(open, open.Start)}, nil
+}
+
+// isFunctionDefinition reports whether the ( at the current token
+// opens a parameter list, which is followed by the { of a body.
+func (P *Parser) isFunctionDefinition() bool {
+	depth := 0
+	for i := P.index; i < len(P.tokens); i++ {
+		switch P.tokens[i].Type {
+		case tokens.ParenOpen:
+			depth++
+		case tokens.ParenClosed:
+			depth--
+			if depth == 0 {
+				return i+1 < len(P.tokens) && P.tokens[i+1].Type == tokens.ScopeOpen
+			}
+		}
 	}
-	return nil, fmt.Errorf("Identifier Expected")
+	return false
+}
+
+// group parses an expression in grouping parens.
+func (P *Parser) group() (Node, error) {
+	open, _ := P.next()
+	value, err := P.pullValue()
+	if err != nil {
+		return nil, err
+	}
+	closed, has := P.peek()
+	if !has {
+		return nil, P.error("E108", "Expected )", P.eof())
+	}
+	if closed.Type != tokens.ParenClosed {
+		return nil, P.error("E108", "Expected )", closed.Span())
+	}
+	P.next()
+	return Group{value, P.MetaFrom(open, open.Start)}, nil
 }
 
 func (P *Parser) parseIdentifier(last tokens.Token) (Node, error) {
@@ -239,13 +587,13 @@ func (P *Parser) parseIdentifier(last tokens.Token) (Node, error) {
 		P.next()
 		current, has := P.next()
 		if !has {
-			return nil, fmt.Errorf("Identifier expected")
+			return nil, P.error("E105", "Identifier expected", P.eof())
 		}
 		node, err := P.parseIdentifier(current)
 		if err != nil {
 			return nil, err
 		}
-		return MemberSelector{Identifier{last.Content, P.Meta(last)}, node, P.Meta(peek)}, nil
+		return MemberSelector{Identifier{last.Content, P.Meta(last)}, node, P.MetaFrom(peek, last.Start)}, nil
 	}
 	return Identifier{last.Content, P.Meta(last)}, nil
 }
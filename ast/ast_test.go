@@ -9,7 +9,7 @@ import (
 	"github.com/worldOneo/rutist/tokens"
 )
 
-var meta = &Meta{tokens.Token{}, "test.go"}
+var meta = &Meta{At: tokens.Token{}, F: "test.go"}
 
 func TestParse(t *testing.T) {
 	type args struct {
@@ -302,6 +302,307 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"left associative",
+			args{tokens.Lexerp(`a - b - c`)},
+			Block{
+				[]Node{
+					BinaryExpression{
+						tokens.OperatorSub,
+						BinaryExpression{tokens.OperatorSub, Identifier{"a", meta}, Identifier{"b", meta}, meta},
+						Identifier{"c", meta},
+						meta,
+					},
+				},
+				meta,
+			},
+			false,
+		},
+		{
+			"comparison",
+			args{tokens.Lexerp(`x = 1 + 2 * 3 == 7`)},
+			Block{
+				[]Node{
+					Assignment{
+						Identifier{"x", meta},
+						BinaryExpression{
+							tokens.OperatorEq,
+							BinaryExpression{
+								tokens.OperatorAdd,
+								Int{1, meta},
+								BinaryExpression{tokens.OperatorMul, Int{2, meta}, Int{3, meta}, meta},
+								meta,
+							},
+							Int{7, meta},
+							meta,
+						},
+						meta,
+					},
+				},
+				meta,
+			},
+			false,
+		},
+		{
+			"unary",
+			args{tokens.Lexerp(`-a * !b.c()`)},
+			Block{
+				[]Node{
+					BinaryExpression{
+						tokens.OperatorMul,
+						UnaryExpression{tokens.OperatorNeg, Identifier{"a", meta}, meta},
+						UnaryExpression{
+							tokens.OperatorNot,
+							Expression{MemberSelector{Identifier{"b", meta}, Identifier{"c", meta}, meta}, []Node{}, meta},
+							meta,
+						},
+						meta,
+					},
+				},
+				meta,
+			},
+			false,
+		},
+		{
+			"group",
+			args{tokens.Lexerp(`(a + b) * c`)},
+			Block{
+				[]Node{
+					BinaryExpression{
+						tokens.OperatorMul,
+						Group{BinaryExpression{tokens.OperatorAdd, Identifier{"a", meta}, Identifier{"b", meta}, meta}, meta},
+						Identifier{"c", meta},
+						meta,
+					},
+				},
+				meta,
+			},
+			false,
+		},
+		{
+			"nested groups with member call",
+			args{tokens.Lexerp(`((a + b) * (c - d)).e(f)`)},
+			Block{
+				[]Node{
+					Expression{
+						MemberSelector{
+							Group{
+								BinaryExpression{
+									tokens.OperatorMul,
+									Group{BinaryExpression{tokens.OperatorAdd, Identifier{"a", meta}, Identifier{"b", meta}, meta}, meta},
+									Group{BinaryExpression{tokens.OperatorSub, Identifier{"c", meta}, Identifier{"d", meta}, meta}, meta},
+									meta,
+								},
+								meta,
+							},
+							Identifier{"e", meta},
+							meta,
+						},
+						[]Node{Identifier{"f", meta}},
+						meta,
+					},
+				},
+				meta,
+			},
+			false,
+		},
+		{
+			"groups as arguments",
+			args{tokens.Lexerp(`x = foo((a).b, (g(1)), -(2))`)},
+			Block{
+				[]Node{
+					Assignment{
+						Identifier{"x", meta},
+						Expression{
+							Identifier{"foo", meta},
+							[]Node{
+								MemberSelector{Group{Identifier{"a", meta}, meta}, Identifier{"b", meta}, meta},
+								Group{Expression{Identifier{"g", meta}, []Node{Int{1, meta}}, meta}, meta},
+								UnaryExpression{tokens.OperatorNeg, Group{Int{2, meta}, meta}, meta},
+							},
+							meta,
+						},
+						meta,
+					},
+				},
+				meta,
+			},
+			false,
+		},
+		{
+			"group call and function definition",
+			args{tokens.Lexerp(`(f)((a) { a })`)},
+			Block{
+				[]Node{
+					Expression{
+						Group{Identifier{"f", meta}, meta},
+						[]Node{
+							FunctionDefinition{
+								Block{[]Node{Identifier{"a", meta}}, meta},
+								[]Identifier{{"a", meta}},
+								meta,
+							},
+						},
+						meta,
+					},
+				},
+				meta,
+			},
+			false,
+		},
+		{
+			"list literal",
+			args{tokens.Lexerp(`l = [1, [a.b], f()].len()`)},
+			Block{
+				[]Node{
+					Assignment{
+						Identifier{"l", meta},
+						Expression{
+							MemberSelector{
+								ListLiteral{
+									[]Node{
+										Int{1, meta},
+										ListLiteral{[]Node{MemberSelector{Identifier{"a", meta}, Identifier{"b", meta}, meta}}, meta},
+										Expression{Identifier{"f", meta}, []Node{}, meta},
+									},
+									meta,
+								},
+								Identifier{"len", meta},
+								meta,
+							},
+							[]Node{},
+							meta,
+						},
+						meta,
+					},
+				},
+				meta,
+			},
+			false,
+		},
+		{
+			"map literal",
+			args{tokens.Lexerp(`m = #{"a": 1, b: [2],
+			}`)},
+			Block{
+				[]Node{
+					Assignment{
+						Identifier{"m", meta},
+						MapLiteral{
+							[]Entry{
+								{String{"a", meta}, Int{1, meta}},
+								{Identifier{"b", meta}, ListLiteral{[]Node{Int{2, meta}}, meta}},
+							},
+							meta,
+						},
+						meta,
+					},
+				},
+				meta,
+			},
+			false,
+		},
+		{
+			"dict literal and scope",
+			args{tokens.Lexerp(`d = {.name = "x", .nested = {.a = 1}}
+			s = { a }
+			e = {}`)},
+			Block{
+				[]Node{
+					Assignment{
+						Identifier{"d", meta},
+						DictLiteral{
+							[]Entry{
+								{Identifier{"name", meta}, String{"x", meta}},
+								{Identifier{"nested", meta}, DictLiteral{[]Entry{{Identifier{"a", meta}, Int{1, meta}}}, meta}},
+							},
+							meta,
+						},
+						meta,
+					},
+					Assignment{Identifier{"s", meta}, Scope{Block{[]Node{Identifier{"a", meta}}, meta}, meta}, meta},
+					Assignment{Identifier{"e", meta}, Scope{Block{[]Node{}, meta}, meta}, meta},
+				},
+				meta,
+			},
+			false,
+		},
+		{
+			"map without colon",
+			args{tokens.Lexerp(`m = #{"a" 1}`)},
+			nil,
+			true,
+		},
+		{
+			"dict without assignment",
+			args{tokens.Lexerp(`d = {.a 1}`)},
+			nil,
+			true,
+		},
+		{
+			"index",
+			args{tokens.Lexerp(`a.b[i + 1][0] = m["k"].c
+			[1]`)},
+			Block{
+				[]Node{
+					Assignment{
+						Index{
+							Index{
+								MemberSelector{Identifier{"a", meta}, Identifier{"b", meta}, meta},
+								BinaryExpression{tokens.OperatorAdd, Identifier{"i", meta}, Int{1, meta}, meta},
+								meta,
+							},
+							Int{0, meta},
+							meta,
+						},
+						MemberSelector{Index{Identifier{"m", meta}, String{"k", meta}, meta}, Identifier{"c", meta}, meta},
+						meta,
+					},
+					ListLiteral{[]Node{Int{1, meta}}, meta},
+				},
+				meta,
+			},
+			false,
+		},
+		{
+			"unclosed index",
+			args{tokens.Lexerp(`a[1`)},
+			nil,
+			true,
+		},
+		{
+			"unclosed list",
+			args{tokens.Lexerp(`l = [1, 2`)},
+			nil,
+			true,
+		},
+		{
+			"unclosed group",
+			args{tokens.Lexerp(`x = (a + b`)},
+			nil,
+			true,
+		},
+		{
+			"logical",
+			args{tokens.Lexerp(`a || b && c || d`)},
+			Block{
+				[]Node{
+					BinaryExpression{
+						tokens.OperatorLor,
+						BinaryExpression{
+							tokens.OperatorLor,
+							Identifier{"a", meta},
+							BinaryExpression{tokens.OperatorLand, Identifier{"b", meta}, Identifier{"c", meta}, meta},
+							meta,
+						},
+						Identifier{"d", meta},
+						meta,
+					},
+				},
+				meta,
+			},
+			false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -310,8 +611,12 @@ func TestParse(t *testing.T) {
 				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantErr {
+				return
+			}
 			walkTree(got, func(node Node) {
 				node.SetToken(meta.At) // Nulling meta for testing, would be to anoying
+				node.SetSpan(meta.Range)
 			})
 			if !reflect.DeepEqual(got, tt.want) {
 				json.NewEncoder(os.Stdout).Encode(got)
@@ -320,3 +625,100 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParse_Spans(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		start tokens.Position
+		end   tokens.Position
+	}{
+		{
+			"binary",
+			`a + b * c`,
+			tokens.Position{Offset: 0, Line: 1, Column: 1},
+			tokens.Position{Offset: 9, Line: 1, Column: 10},
+		},
+		{
+			"call",
+			`x = foo.bar(1, "two")`,
+			tokens.Position{Offset: 0, Line: 1, Column: 1},
+			tokens.Position{Offset: 21, Line: 1, Column: 22},
+		},
+		{
+			"scoped assignment",
+			`@@x = 1`,
+			tokens.Position{Offset: 0, Line: 1, Column: 1},
+			tokens.Position{Offset: 7, Line: 1, Column: 8},
+		},
+		{
+			"function",
+			"\nf = (a) {\n\ta\n}",
+			tokens.Position{Offset: 1, Line: 2, Column: 1},
+			tokens.Position{Offset: 15, Line: 4, Column: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tokens.Lexerp(tt.code), "test.go")
+			if err != nil {
+				t.Fatal(err)
+			}
+			span := got.(Block).Body[0].Span()
+			if span.Start != tt.start || span.End != tt.end {
+				t.Errorf("Span() = %+v, want %+v - %+v", span, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       string
+		want       []string
+		start      tokens.Position
+		statements int
+	}{
+		{"comma", `foo(1 2)`, []string{"E101"}, tokens.Position{Offset: 6, Line: 1, Column: 7}, 1},
+		{"parameter", `f = (a, 1) {}`, []string{"E104"}, tokens.Position{Offset: 8, Line: 1, Column: 9}, 1},
+		{"end", "a =", []string{"E103"}, tokens.Position{Offset: 3, Line: 1, Column: 4}, 1},
+		{
+			"recover",
+			"foo(1 2)\nok = 1\nbar(, 3)\nbaz(1 2)",
+			[]string{"E101", "E102", "E101"},
+			tokens.Position{Offset: 6, Line: 1, Column: 7},
+			4,
+		},
+		{
+			"nested block",
+			"f = () {\n\tfoo(1 2)\n\tok = 1\n}\nbar(1 2)",
+			[]string{"E101", "E101"},
+			tokens.Position{Offset: 16, Line: 2, Column: 8},
+			2,
+		},
+		{"stray brace", "}\na = 1", []string{"E105"}, tokens.Position{Offset: 0, Line: 1, Column: 1}, 2},
+		{"scoped value", "y = @x + 1", []string{"E111"}, tokens.Position{Offset: 4, Line: 1, Column: 5}, 1},
+		{"scoped identifier", "@1 = 2", []string{"E111"}, tokens.Position{Offset: 1, Line: 1, Column: 2}, 1},
+		{"unclosed scope", "f = () {\n\ta = 1", []string{"E106"}, tokens.Position{Offset: 7, Line: 1, Column: 8}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tokens.Lexerp(tt.code), "test.go")
+			list, ok := err.(ErrorList)
+			if !ok {
+				t.Fatalf("Parse() error = %v, want ErrorList", err)
+			}
+			codes := make([]string, len(list))
+			for i, syntax := range list {
+				codes[i] = syntax.Code
+			}
+			if !reflect.DeepEqual(codes, tt.want) || list[0].Span.Start != tt.start || list[0].File != "test.go" {
+				t.Errorf("Parse() error = %v %+v, want %v at %+v", codes, list[0], tt.want, tt.start)
+			}
+			if block, ok := got.(Block); !ok || len(block.Body) != tt.statements {
+				t.Errorf("Parse() = %v, want %d statements", got, tt.statements)
+			}
+		})
+	}
+}
@@ -7,12 +7,17 @@ import (
 type Node interface {
 	Token() tokens.Token
 	File() string
+	Span() tokens.Span
 	SetToken(tokens.Token)
+	SetSpan(tokens.Span)
 }
 
+// Meta locates a node, At is the token which introduced the node
+// and Range spans all of the source the node was parsed from.
 type Meta struct {
-	At tokens.Token
-	F  string
+	At    tokens.Token
+	F     string
+	Range tokens.Span
 }
 
 func (M Meta) Token() tokens.Token {
@@ -23,12 +28,20 @@ func (M Meta) File() string {
 	return M.F
 }
 
+func (M Meta) Span() tokens.Span {
+	return M.Range
+}
+
 func (M *Meta) SetToken(t tokens.Token) {
 	M.At = t
 }
 
+func (M *Meta) SetSpan(s tokens.Span) {
+	M.Range = s
+}
+
 func NewMeta(t tokens.Token, file string) *Meta {
-	return &Meta{t, file}
+	return &Meta{t, file, t.Span()}
 }
 
 type Identifier struct {
@@ -102,10 +115,62 @@ type BinaryExpression struct {
 	*Meta
 }
 
+// ListLiteral is a list written as [a, b, c].
+type ListLiteral struct {
+	Values []Node
+	*Meta
+}
+
+// Entry is a key value pair of a map or dict literal.
+type Entry struct {
+	Key   Node
+	Value Node
+}
+
+// MapLiteral is a map written as #{key: value}.
+type MapLiteral struct {
+	Entries []Entry
+	*Meta
+}
+
+// DictLiteral is a dict written as {.name = value},
+// the keys are identifiers.
+type DictLiteral struct {
+	Entries []Entry
+	*Meta
+}
+
+// Index is an element access written as Object[Index].
+type Index struct {
+	Object Node
+	Index  Node
+	*Meta
+}
+
+// Group is an expression in grouping parens.
+type Group struct {
+	Value Node
+	*Meta
+}
+
+// ScopedIdentifier is the target of an assignment to an outer scope,
+// @name assigns in the nearest scope defining name, @@name in the module.
+type ScopedIdentifier struct {
+	Name   string
+	Module bool
+	*Meta
+}
+
+// ErrorNode replaces a statement which failed to parse.
+type ErrorNode struct {
+	Err *tokens.SyntaxError
+	*Meta
+}
+
 func walkTree(tree Node, f func(node Node)) {
 	f(tree)
 	switch n := tree.(type) {
-	case Identifier, Float, String, Int, Bool:
+	case Identifier, ScopedIdentifier, Float, String, Int, Bool, ErrorNode:
 		return
 	case Block:
 		for _, n := range n.Body {
@@ -134,5 +199,24 @@ func walkTree(tree Node, f func(node Node)) {
 		walkTree(n.Right, f)
 	case UnaryExpression:
 		walkTree(n.Value, f)
+	case Group:
+		walkTree(n.Value, f)
+	case Index:
+		walkTree(n.Object, f)
+		walkTree(n.Index, f)
+	case ListLiteral:
+		for _, n := range n.Values {
+			walkTree(n, f)
+		}
+	case MapLiteral:
+		for _, e := range n.Entries {
+			walkTree(e.Key, f)
+			walkTree(e.Value, f)
+		}
+	case DictLiteral:
+		for _, e := range n.Entries {
+			walkTree(e.Key, f)
+			walkTree(e.Value, f)
+		}
 	}
 }
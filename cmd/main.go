@@ -2,38 +2,88 @@ package main
 
 import (
 	"flag"
-	"io/ioutil"
-	"log"
+	"os"
 	"path/filepath"
+	"strings"
 
-	"github.com/worldOneo/rutist/ast"
-	"github.com/worldOneo/rutist/interpreter"
-	"github.com/worldOneo/rutist/tokens"
+	"github.com/worldOneo/rutist"
+	"github.com/worldOneo/rutist/diagnostics"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "repl" {
+		flags := flag.NewFlagSet("repl", flag.ExitOnError)
+		configure := runtimeFlags(flags)
+		flags.Parse(os.Args[2:])
+		newRepl(os.Stdin, os.Stdout, os.Stderr, configure, colored(os.Stderr)).run()
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		os.Exit(check(os.Args[2:]))
+	}
 	var file string
 	flag.StringVar(&file, "file", "main.rut", "Defines the file to execute")
+	configure := runtimeFlags(flag.CommandLine)
 	flag.Parse()
-	abs, err := filepath.Abs(file)
+	engine := rutist.New()
+	configure(engine)
+	_, err := engine.EvalFile(file)
 	if err != nil {
-		log.Fatal(err)
+		printer := diagnostics.Printer{Color: colored(os.Stderr)}
+		printer.Print(os.Stderr, err)
+		os.Exit(1)
 	}
-	content, err := ioutil.ReadFile(file)
-	if err != nil {
-		log.Fatal(err)
-	}
-	code := string(content)
-	tokens, err := tokens.Lexer(code)
-	if err != nil {
-		log.Fatal(err)
+}
+
+// check parses files without running them and prints every syntax error,
+// the exit code is 1 if any file has errors.
+func check(files []string) int {
+	printer := diagnostics.Printer{Color: colored(os.Stderr)}
+	code := 0
+	for _, file := range files {
+		if _, err := rutist.CompileFile(file); err != nil {
+			printer.Print(os.Stderr, err)
+			code = 1
+		}
 	}
-	parsed, err := ast.Parse(tokens, abs)
-	if err != nil {
-		log.Fatal(err)
+	return code
+}
+
+// colored reports whether f is a terminal which should get ANSI colors.
+func colored(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
 	}
-	_, err = interpreter.Run(abs, parsed)
-	if err != nil {
-		log.Fatal(err)
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// runtimeFlags defines the runtime limits and the import path on flags,
+// the returned func applies them to an engine. The directories given
+// with -I are searched before the ones in RUTIST_PATH.
+func runtimeFlags(flags *flag.FlagSet) func(*rutist.Engine) {
+	defaults := rutist.New().Runtime
+	maxSteps := flags.Int("max-steps", 0, "Limits the steps a script may take, 0 is unlimited")
+	maxDepth := flags.Int("max-depth", defaults.MaxDepth, "Limits the call depth of a script, 0 is unlimited")
+	timeout := flags.Duration("timeout", 0, "Limits the time a script may run, 0 is unlimited")
+	var path pathList
+	flags.Var(&path, "I", "Adds a directory to search for imports, may be repeated")
+	return func(engine *rutist.Engine) {
+		engine.Runtime.Path = append(path, filepath.SplitList(os.Getenv("RUTIST_PATH"))...)
+		engine.Runtime.MaxSteps = *maxSteps
+		engine.Runtime.MaxDepth = *maxDepth
+		engine.Runtime.Timeout = *timeout
 	}
 }
+
+// pathList collects the directories of a repeated flag.
+type pathList []string
+
+func (P *pathList) String() string {
+	return strings.Join(*P, string(filepath.ListSeparator))
+}
+
+func (P *pathList) Set(dir string) error {
+	*P = append(*P, dir)
+	return nil
+}
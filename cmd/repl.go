@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/worldOneo/rutist"
+	"github.com/worldOneo/rutist/diagnostics"
+)
+
+const replName = "<repl>"
+
+type repl struct {
+	engine  *rutist.Engine
+	setup   func(*rutist.Engine)
+	in      *bufio.Scanner
+	out     io.Writer
+	err     io.Writer
+	history []string
+	printer diagnostics.Printer
+}
+
+func newRepl(in io.Reader, out io.Writer, err io.Writer, setup func(*rutist.Engine), color bool) *repl {
+	r := &repl{
+		setup:   setup,
+		in:      bufio.NewScanner(in),
+		out:     out,
+		err:     err,
+		printer: diagnostics.Printer{Color: color, Sources: map[string]string{}},
+	}
+	r.reset()
+	return r
+}
+
+func (R *repl) reset() {
+	R.engine = rutist.New()
+	R.setup(R.engine)
+}
+
+func (R *repl) run() {
+	buff := strings.Builder{}
+	for {
+		if buff.Len() == 0 {
+			fmt.Fprint(R.out, "> ")
+		} else {
+			fmt.Fprint(R.out, ".. ")
+		}
+		if !R.in.Scan() {
+			fmt.Fprintln(R.out)
+			return
+		}
+		line := R.in.Text()
+		if buff.Len() == 0 && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			if !R.command(strings.Fields(strings.TrimSpace(line))) {
+				return
+			}
+			continue
+		}
+		buff.WriteString(line)
+		buff.WriteString("\n")
+		src := buff.String()
+		if !balanced(src) {
+			continue
+		}
+		buff.Reset()
+		if strings.TrimSpace(src) == "" {
+			continue
+		}
+		R.history = append(R.history, strings.TrimRight(src, "\n"))
+		R.eval(src)
+	}
+}
+
+// command runs a meta command and reports whether the repl continues.
+func (R *repl) command(args []string) bool {
+	switch args[0] {
+	case ":quit", ":exit":
+		return false
+	case ":reset":
+		R.reset()
+		fmt.Fprintln(R.out, "Runtime reset")
+	case ":load":
+		if len(args) != 2 {
+			fmt.Fprintln(R.err, "Usage: :load file.rut")
+			return true
+		}
+		if _, err := R.engine.EvalFile(args[1]); err != nil {
+			R.printer.Print(R.err, err)
+		}
+	case ":history":
+		if len(args) == 1 {
+			for i, entry := range R.history {
+				fmt.Fprintf(R.out, "%4d  %s\n", i+1, strings.ReplaceAll(entry, "\n", "\n      "))
+			}
+			return true
+		}
+		n, err := strconv.Atoi(args[1])
+		if len(args) != 2 || err != nil || n < 1 || n > len(R.history) {
+			fmt.Fprintln(R.err, "Usage: :history [n], n is an entry of :history")
+			return true
+		}
+		entry := R.history[n-1]
+		fmt.Fprintln(R.out, entry)
+		R.history = append(R.history, entry)
+		R.eval(entry + "\n")
+	case ":help":
+		fmt.Fprintln(R.out, ":load file.rut  runs a file in the current runtime")
+		fmt.Fprintln(R.out, ":reset          discards all variables")
+		fmt.Fprintln(R.out, ":history        lists the evaluated inputs")
+		fmt.Fprintln(R.out, ":history n      evaluates input n again")
+		fmt.Fprintln(R.out, ":quit           leaves the repl")
+	default:
+		fmt.Fprintf(R.err, "Unknown command %s, try :help\n", args[0])
+	}
+	return true
+}
+
+func (R *repl) eval(src string) {
+	R.printer.Sources[replName] = src
+	program, err := rutist.Compile(src, replName)
+	if err != nil {
+		R.printer.Print(R.err, err)
+		return
+	}
+	val, err := R.engine.Run(program)
+	if err != nil {
+		R.printer.Print(R.err, err)
+		return
+	}
+	if val == nil {
+		return
+	}
+	str, rErr := R.engine.Runtime.Str(val)
+	if rErr != nil {
+		R.printer.Print(R.err, rErr)
+		return
+	}
+	fmt.Fprintln(R.out, str)
+}
+
+// balanced reports whether all braces, brackets and parens of src are closed
+// and no string is left open.
+func balanced(src string) bool {
+	depth := 0
+	inString := false
+	escaped := false
+	comment := false
+	runes := []rune(src)
+	for i, c := range runes {
+		switch {
+		case comment:
+			comment = c != '\n'
+		case inString:
+			if escaped {
+				escaped = false
+			} else if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			comment = true
+		case c == '{' || c == '(' || c == '[':
+			depth++
+		case c == '}' || c == ')' || c == ']':
+			depth--
+		}
+	}
+	return !inString && depth <= 0
+}
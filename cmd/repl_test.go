@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/worldOneo/rutist"
+)
+
+func TestBalanced(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want bool
+	}{
+		{"empty", "", true},
+		{"statement", "a = 1\n", true},
+		{"open brace", "f = () {\n", false},
+		{"closed brace", "f = () {\n\t1\n}\n", true},
+		{"open brace in string", "a = \"{\"\n", true},
+		{"escaped quote", "a = \"say \\\"hi\\\" {\"\n", true},
+		{"escaped backslash", "a = \"\\\\\" + (\n", false},
+		{"open string", "a = \"open\n", false},
+		{"unclosed bracket", "a = [1,\n", false},
+		{"closed bracket", "a = [1,\n2]\n", true},
+		{"open paren", "print(1,\n", false},
+		{"brace in comment", "a = 1 // {\n", true},
+		{"brace after comment", "// comment\nf = () {\n", false},
+		{"extra closer", "}\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := balanced(tt.src); got != tt.want {
+				t.Errorf("balanced(%q) = %v, want %v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepl_Run(t *testing.T) {
+	dir := t.TempDir()
+	loaded := filepath.Join(dir, "loaded.rut")
+	if err := ioutil.WriteFile(loaded, []byte(`fromFile = 5`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		input   string
+		wantOut []string
+		wantErr []string
+	}{
+		{
+			"multi line input",
+			"a = 1\nf = (x) {\n\tx + a\n}\nf(2)\n",
+			[]string{"> > .. .. > 3\n"},
+			nil,
+		},
+		{
+			"history",
+			"a = 1\nf = (x) {\n\tx\n}\n:history\n",
+			[]string{"   1  a = 1\n", "   2  f = (x) {\n      \tx\n      }\n"},
+			nil,
+		},
+		{
+			"history recall",
+			"a = 20\na + 1\n:history 2\n:history\n:history 4\n",
+			[]string{"> 21\n> a + 1\n21\n", "   3  a + 1\n"},
+			[]string{"Usage: :history [n], n is an entry of :history\n"},
+		},
+		{
+			"reset",
+			"a = 1\n:reset\nisNil(a)\n",
+			[]string{"Runtime reset\n", "true\n"},
+			nil,
+		},
+		{
+			"load",
+			":load " + loaded + "\nfromFile\n:load\n",
+			[]string{"5\n"},
+			[]string{"Usage: :load file.rut\n"},
+		},
+		{
+			"help and unknown",
+			":help\n:nope\n",
+			[]string{":reset          discards all variables\n"},
+			[]string{"Unknown command :nope, try :help\n"},
+		},
+		{
+			"error",
+			"foo(1 2)\n",
+			nil,
+			[]string{"error[E101]: Expected comma\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			errOut := &bytes.Buffer{}
+			newRepl(strings.NewReader(tt.input), out, errOut, func(*rutist.Engine) {}, false).run()
+			for _, want := range tt.wantOut {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("out = %q, want %q", out.String(), want)
+				}
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(errOut.String(), want) {
+					t.Errorf("err = %q, want %q", errOut.String(), want)
+				}
+			}
+			if tt.wantErr == nil && errOut.Len() > 0 {
+				t.Errorf("err = %q, want none", errOut.String())
+			}
+		})
+	}
+}
+
+func TestRepl_Quit(t *testing.T) {
+	out := &bytes.Buffer{}
+	newRepl(strings.NewReader(":quit\n1 + 1\n"), out, out, func(*rutist.Engine) {}, false).run()
+	if out.String() != "> " {
+		t.Errorf("out = %q, want the input after :quit to be ignored", out.String())
+	}
+}
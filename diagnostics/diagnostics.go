@@ -0,0 +1,203 @@
+// Package diagnostics renders errors the way compilers do,
+// with the offending source line and a caret under the span.
+package diagnostics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/worldOneo/rutist/ast"
+	"github.com/worldOneo/rutist/interpreter"
+	"github.com/worldOneo/rutist/tokens"
+)
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiRed   = "\x1b[1;31m"
+	ansiBlue  = "\x1b[1;34m"
+)
+
+// Diagnostic is an error located in the source.
+// A Span without a Line has no location.
+type Diagnostic struct {
+	Code    string
+	Message string
+	File    string
+	Span    tokens.Span
+	Notes   []string
+}
+
+func (D *Diagnostic) Error() string {
+	if D.Span.Start.Line == 0 {
+		return D.Message
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", D.File, D.Span.Start.Line, D.Span.Start.Column, D.Message)
+}
+
+// FromError locates err, syntax errors point at their token and
+// runtime errors at their innermost frame, both keep their code.
+// Other errors are kept as message.
+func FromError(err error) *Diagnostic {
+	var diagnostic *Diagnostic
+	if errors.As(err, &diagnostic) {
+		return diagnostic
+	}
+	diagnostic = &Diagnostic{Message: err.Error()}
+	var syntax *tokens.SyntaxError
+	if errors.As(err, &syntax) {
+		diagnostic.Code = syntax.Code
+		diagnostic.Message = syntax.Message
+		diagnostic.File = syntax.File
+		diagnostic.Span = syntax.Span
+	}
+	var runtime *interpreter.Error
+	if !errors.As(err, &runtime) {
+		return diagnostic
+	}
+	frames := runtime.Frames
+	if syntax == nil {
+		diagnostic.Code = runtime.Code
+		diagnostic.Message = runtime.Message()
+		if len(frames) > 0 {
+			diagnostic.File = frames[0].File
+			diagnostic.Span = frameSpan(frames[0])
+			frames = frames[1:]
+		}
+	}
+	for _, frame := range frames {
+		diagnostic.Notes = append(diagnostic.Notes, "at "+frame.String())
+	}
+	for cause := runtime.Cause; cause != nil; cause = cause.Cause {
+		diagnostic.Notes = append(diagnostic.Notes, "caused by: "+cause.Summary())
+	}
+	return diagnostic
+}
+
+func frameSpan(frame interpreter.Frame) tokens.Span {
+	start := tokens.Position{Line: frame.Line, Column: frame.Column}
+	return tokens.Span{Start: start, End: start}
+}
+
+// Printer renders diagnostics, sources are read from disk
+// unless they are given in Sources, like the input of a repl.
+type Printer struct {
+	Color   bool
+	Sources map[string]string
+}
+
+// Print renders err as diagnostic to w,
+// every error of a syntax error list is rendered.
+func (P *Printer) Print(w io.Writer, err error) {
+	var list ast.ErrorList
+	if !errors.As(err, &list) {
+		io.WriteString(w, P.Render(FromError(err)))
+		return
+	}
+	for i, syntax := range list {
+		if i > 0 {
+			io.WriteString(w, "\n")
+		}
+		io.WriteString(w, P.Render(FromError(syntax)))
+	}
+}
+
+// Render formats the diagnostic with its source line and notes.
+func (P *Printer) Render(d *Diagnostic) string {
+	builder := strings.Builder{}
+	builder.WriteString(P.paint(ansiRed, "error"))
+	if d.Code != "" {
+		builder.WriteString(P.paint(ansiRed, "["+d.Code+"]"))
+	}
+	builder.WriteString(P.paint(ansiBold, ": "+d.Message))
+	builder.WriteString("\n")
+	start := d.Span.Start
+	if start.Line == 0 {
+		P.notes(&builder, d.Notes, "")
+		return builder.String()
+	}
+	gutter := strings.Repeat(" ", len(fmt.Sprint(start.Line)))
+	fmt.Fprintf(&builder, "%s%s %s:%d", gutter, P.paint(ansiBlue, "-->"), d.File, start.Line)
+	if start.Column > 0 {
+		fmt.Fprintf(&builder, ":%d", start.Column)
+	}
+	builder.WriteString("\n")
+	line, ok := P.line(d.File, start.Line)
+	if ok {
+		fmt.Fprintf(&builder, "%s %s\n", gutter, P.paint(ansiBlue, "|"))
+		fmt.Fprintf(&builder, "%s %s %s\n", P.paint(ansiBlue, fmt.Sprint(start.Line)), P.paint(ansiBlue, "|"), line)
+		if start.Column > 0 {
+			fmt.Fprintf(&builder, "%s %s %s%s\n", gutter, P.paint(ansiBlue, "|"),
+				indent(line, start.Column), P.paint(ansiRed, strings.Repeat("^", width(line, d.Span))))
+		}
+	}
+	P.notes(&builder, d.Notes, gutter)
+	return builder.String()
+}
+
+func (P *Printer) notes(builder *strings.Builder, notes []string, gutter string) {
+	for _, note := range notes {
+		fmt.Fprintf(builder, "%s %s note: %s\n", gutter, P.paint(ansiBlue, "="), note)
+	}
+}
+
+func (P *Printer) paint(color string, text string) string {
+	if !P.Color {
+		return text
+	}
+	return color + text + ansiReset
+}
+
+// line is the 1-based line of file without its line break.
+func (P *Printer) line(file string, line int) (string, bool) {
+	src, ok := P.Sources[file]
+	if !ok {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", false
+		}
+		src = string(content)
+	}
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	lines := strings.Split(strings.ReplaceAll(src, "\r", "\n"), "\n")
+	if line > len(lines) {
+		return "", false
+	}
+	return lines[line-1], true
+}
+
+// indent aligns the caret under column, tabs are kept to match the source.
+func indent(line string, column int) string {
+	builder := strings.Builder{}
+	for i, c := range []rune(line) {
+		if i >= column-1 {
+			break
+		}
+		if c == '\t' {
+			builder.WriteRune('\t')
+		} else {
+			builder.WriteRune(' ')
+		}
+	}
+	return builder.String()
+}
+
+// width is the length of the underline, spans over multiple lines
+// are underlined up to the end of the first one.
+func width(line string, span tokens.Span) int {
+	rest := len([]rune(line)) - span.Start.Column + 1
+	w := rest
+	if span.End.Line == span.Start.Line {
+		w = span.End.Column - span.Start.Column
+	}
+	if w > rest {
+		w = rest
+	}
+	if w < 1 {
+		return 1
+	}
+	return w
+}
@@ -0,0 +1,137 @@
+package diagnostics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/worldOneo/rutist"
+)
+
+func TestPrinter_Render(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{
+			"parse error",
+			"foo(1 2)",
+			"error[E101]: Expected comma\n" +
+				" --> test.rut:1:7\n" +
+				"  |\n" +
+				"1 | foo(1 2)\n" +
+				"  |       ^\n",
+		},
+		{
+			"lexer error",
+			"a = 1\nb = \"open",
+			"error[E002]: Incomplete string\n" +
+				" --> test.rut:2:5\n" +
+				"  |\n" +
+				"2 | b = \"open\n" +
+				"  |     ^^^^^\n",
+		},
+		{
+			"runtime error",
+			"f = () {\n\tthrow(\"failed\")\n}\nf()",
+			"error: failed\n" +
+				" --> test.rut:2:2\n" +
+				"  |\n" +
+				"2 | \tthrow(\"failed\")\n" +
+				"  | \t^\n" +
+				"  = note: at f (test.rut:4:1)\n",
+		},
+		{
+			"invalid operator",
+			"a = isNil + 1",
+			"error[E201]: Invalid operator\n" +
+				" --> test.rut:1:11\n" +
+				"  |\n" +
+				"1 | a = isNil + 1\n" +
+				"  |           ^\n",
+		},
+		{
+			"operator error",
+			"print([1] + 2)",
+			"error: List: Can only add lists\n" +
+				" --> test.rut:1:11\n" +
+				"  |\n" +
+				"1 | print([1] + 2)\n" +
+				"  |           ^\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			printer := Printer{Sources: map[string]string{"test.rut": tt.code}}
+			err := run(tt.code)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if got := printer.Render(FromError(err)); got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrinter_RuntimeCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"stack overflow", "f = () { f() }\nf()", "error[E208]: Stack overflow"},
+		{"undefined scoped", "f = () { @missing = 1 }\nf()", "error[E206]: Assign: @missing is not defined"},
+		{"thrown", `throw("failed")`, "error: failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			printer := Printer{Sources: map[string]string{"test.rut": tt.code}}
+			if got := printer.Render(FromError(run(tt.code))); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("Render() = %q, want prefix %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrinter_Color(t *testing.T) {
+	printer := Printer{Color: true, Sources: map[string]string{"test.rut": "foo(1 2)"}}
+	got := printer.Render(FromError(run("foo(1 2)")))
+	if !strings.Contains(got, ansiRed+"^"+ansiReset) {
+		t.Errorf("Render() = %q, want a red caret", got)
+	}
+	printer.Color = false
+	if got := printer.Render(FromError(run("foo(1 2)"))); strings.Contains(got, "\x1b") {
+		t.Errorf("Render() = %q, want no escapes", got)
+	}
+}
+
+func run(code string) error {
+	program, err := rutist.Compile(code, "test.rut")
+	if err != nil {
+		return err
+	}
+	_, err = rutist.New().Run(program)
+	return err
+}
+
+func TestPrinter_PrintList(t *testing.T) {
+	code := "foo(1 2)\nbar(, 3)"
+	printer := Printer{Sources: map[string]string{"test.rut": code}}
+	builder := strings.Builder{}
+	printer.Print(&builder, run(code))
+	want := "error[E101]: Expected comma\n" +
+		" --> test.rut:1:7\n" +
+		"  |\n" +
+		"1 | foo(1 2)\n" +
+		"  |       ^\n" +
+		"\n" +
+		"error[E102]: Unexpected comma\n" +
+		" --> test.rut:2:5\n" +
+		"  |\n" +
+		"2 | bar(, 3)\n" +
+		"  |     ^\n"
+	if got := builder.String(); got != want {
+		t.Errorf("Print() = %q, want %q", got, want)
+	}
+}
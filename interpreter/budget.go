@@ -0,0 +1,85 @@
+package interpreter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/worldOneo/rutist/ast"
+)
+
+// budget tracks the resources used by a run,
+// it is shared with the runtimes of imported files.
+type budget struct {
+	ctx   context.Context
+	steps int
+	depth int
+}
+
+// RunContext runs the program until it completes, ctx is done or
+// one of the limits of the runtime is exceeded.
+// Exceeding a limit raises an error which can be caught by try,
+// but every following step raises it again.
+func (R *Runtime) RunContext(ctx context.Context, program ast.Node) (Value, *Error) {
+	return R.withContext(ctx, func() (Value, *Error) {
+		return R.Run(program)
+	})
+}
+
+// InvokeContext is like Invoke but limited like RunContext.
+func (R *Runtime) InvokeContext(ctx context.Context, value Value, args []Value) (Value, *Error) {
+	return R.withContext(ctx, func() (Value, *Error) {
+		return R.Invoke(value, args)
+	})
+}
+
+func (R *Runtime) withContext(ctx context.Context, run func() (Value, *Error)) (Value, *Error) {
+	if R.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, R.Timeout)
+		defer cancel()
+	}
+	if R.budget.ctx != nil {
+		return R.reenter(ctx, run)
+	}
+	prev := *R.budget
+	*R.budget = budget{ctx: ctx}
+	defer func() { *R.budget = prev }()
+	return run()
+}
+
+// reenter runs a host call made while a script is running,
+// the steps and depth keep counting and the running context still applies.
+func (R *Runtime) reenter(ctx context.Context, run func() (Value, *Error)) (Value, *Error) {
+	inner, cancel := context.WithCancel(R.budget.ctx)
+	defer cancel()
+	if ctx.Done() != nil {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				cancel()
+			case <-done:
+			}
+		}()
+	}
+	prev := R.budget.ctx
+	R.budget.ctx = inner
+	defer func() { R.budget.ctx = prev }()
+	return run()
+}
+
+// step is called for every statement, call and loop iteration.
+func (R *Runtime) step() *Error {
+	R.budget.steps++
+	if R.MaxSteps > 0 && R.budget.steps > R.MaxSteps {
+		return &Error{Code: "E209", Err: fmt.Errorf("Step limit of %d exceeded", R.MaxSteps)}
+	}
+	if R.budget.ctx == nil {
+		return nil
+	}
+	if err := R.budget.ctx.Err(); err != nil {
+		return &Error{Code: "E210", Err: err}
+	}
+	return nil
+}
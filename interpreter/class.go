@@ -99,3 +99,8 @@ func builtinClass(r *Runtime, v []Value) (Value, *Error) {
 	}
 	return Constructor{class}, nil
 }
+
+func instanceOf(v Value, class Constructor) bool {
+	inst, ok := v.(*Instance)
+	return ok && inst.of == class.template
+}
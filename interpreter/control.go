@@ -0,0 +1,91 @@
+package interpreter
+
+import "errors"
+
+// signal is the control flow an Error carries instead of a failure.
+type signal int
+
+const (
+	signalNone signal = iota
+	signalBreak
+	signalContinue
+	signalReturn
+)
+
+// flow tracks the loops and functions a signal may stop at.
+// Loops are only visible inside the function they run in,
+// except for loop bodies which are functions themselves.
+type flow struct {
+	loops     int
+	functions int
+	loopBody  bool
+}
+
+func builtinBreak(r *Runtime, args []Value) (Value, *Error) {
+	if r.flow.loops == 0 {
+		return builtinThrow(r, []Value{String("Break: Used outside of a loop")})
+	}
+	return nil, &Error{Err: errors.New("break"), signal: signalBreak}
+}
+
+func builtinContinue(r *Runtime, args []Value) (Value, *Error) {
+	if r.flow.loops == 0 {
+		return builtinThrow(r, []Value{String("Continue: Used outside of a loop")})
+	}
+	return nil, &Error{Err: errors.New("continue"), signal: signalContinue}
+}
+
+func builtinReturn(r *Runtime, args []Value) (Value, *Error) {
+	if r.flow.functions == 0 {
+		return builtinThrow(r, []Value{String("Return: Used outside of a function")})
+	}
+	if len(args) > 1 {
+		return builtinThrow(r, []Value{String("Return: Requires at most 1 parameter")})
+	}
+	var value Value
+	if len(args) == 1 {
+		value = args[0]
+	}
+	return nil, &Error{Err: errors.New("return"), Value: value, signal: signalReturn}
+}
+
+// runLoopBody runs one iteration of a loop and reports whether
+// the loop continues, break and continue are consumed here.
+func (R *Runtime) runLoopBody(body Value, args []Value) (Value, bool, *Error) {
+	R.flow.loopBody = true
+	val, err := builtinRun(R, append([]Value{body}, args...))
+	R.flow.loopBody = false
+	if err == nil {
+		return val, true, nil
+	}
+	switch err.signal {
+	case signalBreak:
+		return nil, false, nil
+	case signalContinue:
+		return nil, true, nil
+	}
+	return nil, false, err
+}
+
+// enterLoop counts a running loop, the returned func leaves it.
+func (R *Runtime) enterLoop() func() {
+	R.flow.loops++
+	return func() { R.flow.loops-- }
+}
+
+// enterFunction hides the loops of the caller from the function
+// unless it is the body of a loop, the returned func restores them.
+// A loop body is no function of its own, return passes through it
+// to the enclosing function.
+func (R *Runtime) enterFunction() func() {
+	saved := R.flow
+	if !R.flow.loopBody {
+		R.flow.loops = 0
+		R.flow.functions++
+	}
+	R.flow.loopBody = false
+	return func() {
+		R.flow.loops = saved.loops
+		R.flow.functions = saved.functions
+	}
+}
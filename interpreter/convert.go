@@ -0,0 +1,568 @@
+package interpreter
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// GoValue exposes a Go struct, pointer or slice to scripts.
+// Struct fields and methods are reachable as members,
+// fields can be renamed with a `rutist:"name"` tag.
+type GoValue struct {
+	value reflect.Value
+}
+
+var goValueNatives = NativeMap{}
+
+var (
+	valueType = reflect.TypeOf((*Value)(nil)).Elem()
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+func (GoValue) Type() String {
+	return "builtin+go"
+}
+
+func (GoValue) Natives() NativeMap {
+	return goValueNatives
+}
+
+func (G GoValue) Interface() interface{} {
+	return G.value.Interface()
+}
+
+func init() {
+	goValueNatives[NativeStr] = Function(func(r *Runtime, v []Value) (Value, *Error) {
+		return String(fmt.Sprint(v[0].(GoValue).Interface())), nil
+	})
+	goValueNatives[NativeLen] = Function(func(r *Runtime, v []Value) (Value, *Error) {
+		val := indirect(v[0].(GoValue).value)
+		switch val.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map, reflect.String:
+			return Int(val.Len()), nil
+		}
+		return builtinThrow(r, []Value{String("Go: Value has no length")})
+	})
+	goValueNatives[NativeGetIndex] = goSequence(goSliceGet)
+	goValueNatives[NativeSetIndex] = goSequence(goSliceSet)
+	goValueNatives[NativeGetMember] = Function(goValueGetMember)
+	goValueNatives[NativeSetMember] = Function(goValueSetMember)
+}
+
+func goValueGetMember(r *Runtime, v []Value) (Value, *Error) {
+	obj := v[0].(GoValue)
+	name, ok := v[1].(String)
+	if !ok {
+		return nil, nil
+	}
+	if method := obj.value.MethodByName(string(name)); method.IsValid() {
+		return unbound(goFunction(method)), nil
+	}
+	val := indirect(obj.value)
+	switch val.Kind() {
+	case reflect.Struct:
+		field, ok := structField(val, string(name))
+		if !ok {
+			return nil, nil
+		}
+		member, err := goFieldValue(field)
+		if err != nil {
+			return nil, &Error{Err: err}
+		}
+		if fn, ok := member.(Function); ok {
+			return unbound(fn), nil
+		}
+		return member, nil
+	case reflect.Slice, reflect.Array:
+		switch name {
+		case "len":
+			return goValueNatives[NativeLen], nil
+		case "get":
+			return Function(goSliceGet), nil
+		case "set":
+			return Function(goSliceSet), nil
+		}
+	}
+	return nil, nil
+}
+
+func goValueSetMember(r *Runtime, v []Value) (Value, *Error) {
+	name, ok := v[1].(String)
+	if !ok {
+		return builtinThrow(r, []Value{String("Go: Member name must be string")})
+	}
+	val := indirect(v[0].(GoValue).value)
+	if val.Kind() != reflect.Struct {
+		return builtinThrow(r, []Value{String("Go: Value has no members")})
+	}
+	field, ok := structField(val, string(name))
+	if !ok || !field.CanSet() {
+		return builtinThrow(r, []Value{String(fmt.Sprintf("Go: Member %s is not assignable", name))})
+	}
+	converted, err := fromValue(r, v[2], field.Type())
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+	field.Set(converted)
+	return nil, nil
+}
+
+// goSequence only applies f to wrapped slices and arrays.
+func goSequence(f Function) Function {
+	return func(r *Runtime, v []Value) (Value, *Error) {
+		kind := indirect(v[0].(GoValue).value).Kind()
+		if kind != reflect.Slice && kind != reflect.Array {
+			return builtinThrow(r, []Value{String("Go: Value can't be indexed")})
+		}
+		return f(r, v)
+	}
+}
+
+func goSliceGet(r *Runtime, v []Value) (Value, *Error) {
+	if len(v) != 2 {
+		return builtinThrow(r, []Value{String("Go: Get Requires exactly 1 parameter")})
+	}
+	val := indirect(v[0].(GoValue).value)
+	index, ok := v[1].(Int)
+	if !ok || int(index) < 0 || int(index) >= val.Len() {
+		return builtinThrow(r, []Value{String("Go: Index out of bounds")})
+	}
+	res, err := goFieldValue(val.Index(int(index)))
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+	return res, nil
+}
+
+func goSliceSet(r *Runtime, v []Value) (Value, *Error) {
+	if len(v) != 3 {
+		return builtinThrow(r, []Value{String("Go: Set Requires exactly 2 parameters")})
+	}
+	val := indirect(v[0].(GoValue).value)
+	index, ok := v[1].(Int)
+	if !ok || int(index) < 0 || int(index) >= val.Len() {
+		return builtinThrow(r, []Value{String("Go: Index out of bounds")})
+	}
+	elem := val.Index(int(index))
+	if !elem.CanSet() {
+		return builtinThrow(r, []Value{String("Go: Value is not assignable")})
+	}
+	converted, err := fromValue(r, v[2], elem.Type())
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+	elem.Set(converted)
+	return nil, nil
+}
+
+// goFieldValue keeps addressable structs wrapped, so assignments to
+// nested members write through to the original Go value.
+func goFieldValue(field reflect.Value) (Value, error) {
+	if field.Kind() == reflect.Struct && field.CanAddr() {
+		return GoValue{field.Addr()}, nil
+	}
+	return toValue(field)
+}
+
+func structField(val reflect.Value, name string) (reflect.Value, bool) {
+	t := val.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		if tag := f.Tag.Get("rutist"); tag == name || (tag == "" && f.Name == name) {
+			return val.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func indirect(val reflect.Value) reflect.Value {
+	for val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+	return val
+}
+
+// unbound drops the receiver which is passed to member functions.
+func unbound(f Function) Function {
+	return func(r *Runtime, v []Value) (Value, *Error) {
+		return f(r, v[1:])
+	}
+}
+
+// ToValue converts a Go value into a Rutist value.
+// Basic types are copied, maps become Map, funcs become Function
+// and structs, pointers and slices are wrapped into a GoValue.
+func ToValue(v interface{}) (Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if val, ok := v.(Value); ok {
+		return val, nil
+	}
+	return toValue(reflect.ValueOf(v))
+}
+
+func toValue(val reflect.Value) (Value, error) {
+	if !val.IsValid() {
+		return nil, nil
+	}
+	if val.Type().Implements(valueType) && val.CanInterface() {
+		if val.Kind() == reflect.Interface && val.IsNil() {
+			return nil, nil
+		}
+		return val.Interface().(Value), nil
+	}
+	switch val.Kind() {
+	case reflect.String:
+		return String(val.String()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Int(val.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return Int(val.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return Float(val.Float()), nil
+	case reflect.Bool:
+		return Bool(val.Bool()), nil
+	case reflect.Interface:
+		if val.IsNil() {
+			return nil, nil
+		}
+		if err, ok := val.Interface().(error); ok && val.Type() == errorType {
+			return &Error{Err: err}, nil
+		}
+		return toValue(val.Elem())
+	case reflect.Ptr:
+		if val.IsNil() {
+			return nil, nil
+		}
+		if val.Elem().Kind() == reflect.Struct {
+			return GoValue{val}, nil
+		}
+		return toValue(val.Elem())
+	case reflect.Map:
+		if val.IsNil() {
+			return nil, nil
+		}
+		m := Map{}
+		iter := val.MapRange()
+		for iter.Next() {
+			key, err := toValue(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			elem, err := toValue(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			m[key] = elem
+		}
+		return m, nil
+	case reflect.Func:
+		if val.IsNil() {
+			return nil, nil
+		}
+		return goFunction(val), nil
+	case reflect.Struct, reflect.Slice, reflect.Array:
+		return GoValue{val}, nil
+	}
+	return nil, fmt.Errorf("cannot convert %s to a value", val.Type())
+}
+
+func goFunction(fn reflect.Value) Function {
+	t := fn.Type()
+	return func(r *Runtime, args []Value) (Value, *Error) {
+		fixed := t.NumIn()
+		if t.IsVariadic() {
+			fixed--
+		}
+		if len(args) < fixed || (!t.IsVariadic() && len(args) > fixed) {
+			return builtinThrow(r, []Value{String(fmt.Sprintf("Go: Requires %d parameters got %d", fixed, len(args)))})
+		}
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			var param reflect.Type
+			if i < fixed {
+				param = t.In(i)
+			} else {
+				param = t.In(fixed).Elem()
+			}
+			val, err := fromValue(r, arg, param)
+			if err != nil {
+				return nil, &Error{Err: fmt.Errorf("Go: Parameter %d: %v", i+1, err)}
+			}
+			in[i] = val
+		}
+		out := fn.Call(in)
+		if n := len(out); n > 0 && t.Out(n-1) == errorType {
+			if !out[n-1].IsNil() {
+				return nil, &Error{Err: out[n-1].Interface().(error)}
+			}
+			out = out[:n-1]
+		}
+		var res reflect.Value
+		switch len(out) {
+		case 0:
+			return nil, nil
+		case 1:
+			res = out[0]
+		default:
+			results := make([]interface{}, len(out))
+			for i := range out {
+				results[i] = out[i].Interface()
+			}
+			res = reflect.ValueOf(results)
+		}
+		val, err := toValue(res)
+		if err != nil {
+			return nil, &Error{Err: err}
+		}
+		return val, nil
+	}
+}
+
+// FromValue stores the Rutist value v into the Go value dst points to.
+// The runtime is used to call script functions converted to Go funcs,
+// these funcs must return an error last which reports script errors.
+func FromValue(r *Runtime, v Value, dst interface{}) error {
+	ptr := reflect.ValueOf(dst)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return fmt.Errorf("destination must be a non nil pointer")
+	}
+	val, err := fromValue(r, v, ptr.Elem().Type())
+	if err != nil {
+		return err
+	}
+	ptr.Elem().Set(val)
+	return nil
+}
+
+func fromValue(r *Runtime, v Value, t reflect.Type) (reflect.Value, error) {
+	if v == nil {
+		return reflect.Zero(t), nil
+	}
+	if goVal, ok := v.(GoValue); ok {
+		if goVal.value.Type().AssignableTo(t) {
+			return assignable(goVal.value, t), nil
+		}
+		if t.Kind() != reflect.Ptr && goVal.value.Kind() == reflect.Ptr && goVal.value.Elem().Type().AssignableTo(t) {
+			return assignable(goVal.value.Elem(), t), nil
+		}
+	}
+	if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+		natural, err := fromValue(r, v, naturalType(v))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return assignable(natural, t), nil
+	}
+	if reflect.TypeOf(v).AssignableTo(t) {
+		return assignable(reflect.ValueOf(v), t), nil
+	}
+	mismatch := fmt.Errorf("cannot convert %s to %s", v.Type(), t)
+	switch t.Kind() {
+	case reflect.String:
+		if str, ok := v.(String); ok {
+			return reflect.ValueOf(string(str)).Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := v.(Int); ok {
+			return reflect.ValueOf(int64(i)).Convert(t), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i, ok := v.(Int); ok && i >= 0 {
+			return reflect.ValueOf(uint64(i)).Convert(t), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch f := v.(type) {
+		case Float:
+			return reflect.ValueOf(float64(f)).Convert(t), nil
+		case Int:
+			return reflect.ValueOf(float64(f)).Convert(t), nil
+		}
+	case reflect.Bool:
+		if b, ok := v.(Bool); ok {
+			return reflect.ValueOf(bool(b)).Convert(t), nil
+		}
+	case reflect.Interface:
+		if t == errorType {
+			if err, ok := v.(*Error); ok {
+				return assignable(reflect.ValueOf(err), t), nil
+			}
+		}
+	case reflect.Ptr:
+		elem, err := fromValue(r, v, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	case reflect.Map:
+		entries, ok := mapEntries(v)
+		if !ok {
+			return reflect.Value{}, mismatch
+		}
+		m := reflect.MakeMapWithSize(t, len(entries))
+		for key, elem := range entries {
+			k, err := fromValue(r, key, t.Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			e, err := fromValue(r, elem, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			m.SetMapIndex(k, e)
+		}
+		return m, nil
+	case reflect.Struct:
+		entries, ok := mapEntries(v)
+		if !ok {
+			return reflect.Value{}, mismatch
+		}
+		s := reflect.New(t).Elem()
+		for key, elem := range entries {
+			name, ok := key.(String)
+			if !ok {
+				continue
+			}
+			field, ok := structField(s, string(name))
+			if !ok {
+				continue
+			}
+			val, err := fromValue(r, elem, field.Type())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("member %s: %v", name, err)
+			}
+			field.Set(val)
+		}
+		return s, nil
+	case reflect.Slice:
+		if list, ok := v.(*List); ok {
+			s := reflect.MakeSlice(t, len(list.Values), len(list.Values))
+			for i, elem := range list.Values {
+				val, err := fromValue(r, elem, t.Elem())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				s.Index(i).Set(val)
+			}
+			return s, nil
+		}
+		goVal, ok := v.(GoValue)
+		if !ok {
+			return reflect.Value{}, mismatch
+		}
+		src := indirect(goVal.value)
+		if src.Kind() != reflect.Slice && src.Kind() != reflect.Array {
+			return reflect.Value{}, mismatch
+		}
+		s := reflect.MakeSlice(t, src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			elem, err := toValue(src.Index(i))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			val, err := fromValue(r, elem, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			s.Index(i).Set(val)
+		}
+		return s, nil
+	case reflect.Func:
+		if r == nil || r.getNativeField(v, NativeRun) == nil {
+			return reflect.Value{}, mismatch
+		}
+		if n := t.NumOut(); n == 0 || n > 2 || t.Out(n-1) != errorType {
+			return reflect.Value{}, fmt.Errorf("%s: script functions convert to funcs returning an error and at most one value", mismatch)
+		}
+		return scriptFunction(r, v, t), nil
+	}
+	return reflect.Value{}, mismatch
+}
+
+// assignable returns val typed exactly as t,
+// which reflect requires for results of MakeFunc.
+func assignable(val reflect.Value, t reflect.Type) reflect.Value {
+	if val.Type() == t {
+		return val
+	}
+	res := reflect.New(t).Elem()
+	res.Set(val)
+	return res
+}
+
+// naturalType is the Go type a value converts to if the destination
+// does not specify one.
+func naturalType(v Value) reflect.Type {
+	switch v.(type) {
+	case String:
+		return reflect.TypeOf("")
+	case Int:
+		return reflect.TypeOf(0)
+	case Float:
+		return reflect.TypeOf(0.0)
+	case Bool:
+		return reflect.TypeOf(false)
+	case Map:
+		return reflect.TypeOf(map[interface{}]interface{}{})
+	case Dict:
+		return reflect.TypeOf(map[string]interface{}{})
+	case *List:
+		return reflect.TypeOf([]interface{}{})
+	case *Error:
+		return errorType
+	case GoValue:
+		return v.(GoValue).value.Type()
+	}
+	return reflect.TypeOf(v)
+}
+
+func mapEntries(v Value) (Map, bool) {
+	switch m := v.(type) {
+	case Map:
+		return m, true
+	case Dict:
+		return Map(m), true
+	}
+	return nil, false
+}
+
+// scriptFunction wraps fn in a Go func of type t, which returns
+// an error and at most one value before it.
+func scriptFunction(r *Runtime, fn Value, t reflect.Type) reflect.Value {
+	return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		out := make([]reflect.Value, t.NumOut())
+		for i := range out {
+			out[i] = reflect.Zero(t.Out(i))
+		}
+		fail := func(err error) []reflect.Value {
+			out[len(out)-1] = reflect.ValueOf(&err).Elem()
+			return out
+		}
+		args := make([]Value, len(in))
+		for i := range in {
+			arg, err := toValue(in[i])
+			if err != nil {
+				return fail(err)
+			}
+			args[i] = arg
+		}
+		res, rErr := r.Invoke(fn, args)
+		if rErr != nil {
+			return fail(rErr)
+		}
+		if len(out) > 0 && t.Out(0) != errorType {
+			val, err := fromValue(r, res, t.Out(0))
+			if err != nil {
+				return fail(err)
+			}
+			out[0] = val
+		}
+		return out
+	})
+}
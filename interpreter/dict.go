@@ -7,6 +7,19 @@ func (Dict) Type() String {
 var dictNatives = NativeMap{}
 
 func init() {
+	dictNatives[NativeGetIndex] = Function(func(r *Runtime, v []Value) (Value, *Error) {
+		if !hashable(v[1]) {
+			return builtinThrow(r, []Value{String("Dict: Key of type " + typeName(v[1]) + " is not hashable")})
+		}
+		return v[0].(Dict)[v[1]], nil
+	})
+	dictNatives[NativeSetIndex] = Function(func(r *Runtime, v []Value) (Value, *Error) {
+		if !hashable(v[1]) {
+			return builtinThrow(r, []Value{String("Dict: Key of type " + typeName(v[1]) + " is not hashable")})
+		}
+		v[0].(Dict)[v[1]] = v[2]
+		return nil, nil
+	})
 	dictNatives[NativeSetMember] = Function(func(r *Runtime, v []Value) (Value, *Error) {
 		v[0].(Dict)[v[1]] = v[2]
 		return nil, nil
@@ -1,9 +1,35 @@
 package interpreter
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
 var errorNatives = NativeMap{}
 
+// Frame is a location an error passed on its way up,
+// Function is the name of the function called at this location.
+type Frame struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column,omitempty"`
+	Function string `json:"function,omitempty"`
+}
+
+// Error is raised by throw and by failing natives.
+// Value is the value passed to throw, if any. Code identifies
+// errors raised by the interpreter itself, like E208 for a stack overflow.
+// Errors with a signal are break, continue and return
+// on their way to the loop or function they stop at.
 type Error struct {
-	Err error
+	Code    string
+	Err     error
+	Frames  []Frame
+	Cause   *Error
+	Value   Value
+	omitted int
+	signal  signal
 }
 
 func (Error) Type() String {
@@ -12,4 +38,146 @@ func (Error) Type() String {
 
 func (Error) Natives() NativeMap {
 	return errorNatives
-}
\ No newline at end of file
+}
+
+func init() {
+	errorNatives[NativeStr] = Function(func(r *Runtime, v []Value) (Value, *Error) {
+		return String(v[0].(*Error).Error()), nil
+	})
+	errorNatives[NativeGetMember] = Function(func(r *Runtime, v []Value) (Value, *Error) {
+		err := v[0].(*Error)
+		member, ok := v[1].(String)
+		if !ok {
+			return nil, nil
+		}
+		switch member {
+		case "message":
+			return String(err.Message()), nil
+		case "code":
+			return String(err.Code), nil
+		case "trace":
+			trace := make([]Value, len(err.Frames))
+			for i, frame := range err.Frames {
+				trace[i] = frame.dict()
+			}
+			return &List{trace}, nil
+		case "cause":
+			if err.Cause == nil {
+				return nil, nil
+			}
+			return err.Cause, nil
+		case "value":
+			return err.Value, nil
+		}
+		if err.Value == nil {
+			return nil, nil
+		}
+		return r.getMember(err.Value, member)
+	})
+}
+
+func (F Frame) String() string {
+	location := fmt.Sprintf("%s:%d", F.File, F.Line)
+	if F.Column > 0 {
+		location = fmt.Sprintf("%s:%d", location, F.Column)
+	}
+	if F.Function == "" {
+		return location
+	}
+	return fmt.Sprintf("%s (%s)", F.Function, location)
+}
+
+func (F Frame) dict() Dict {
+	return Dict{
+		String("file"):     String(F.File),
+		String("line"):     Int(F.Line),
+		String("column"):   Int(F.Column),
+		String("function"): String(F.Function),
+	}
+}
+
+// Message is the error without its trace.
+func (E *Error) Message() string {
+	if E.Err == nil {
+		return ""
+	}
+	return E.Err.Error()
+}
+
+func (E *Error) Error() string {
+	builder := strings.Builder{}
+	builder.WriteString(E.Message())
+	for _, frame := range E.Frames {
+		builder.WriteString("\n\tat ")
+		builder.WriteString(frame.String())
+	}
+	if E.omitted > 0 {
+		fmt.Fprintf(&builder, "\n\t... %d more", E.omitted)
+	}
+	if E.Cause != nil {
+		builder.WriteString("\nCaused by: ")
+		builder.WriteString(E.Cause.Error())
+	}
+	return builder.String()
+}
+
+// Summary is the message with the innermost frame only.
+func (E *Error) Summary() string {
+	if len(E.Frames) == 0 {
+		return E.Message()
+	}
+	return fmt.Sprintf("%s (at %s)", E.Message(), E.Frames[0])
+}
+
+func (E *Error) Unwrap() error {
+	if E.Cause != nil {
+		return E.Cause
+	}
+	return E.Err
+}
+
+func (E *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Code    string  `json:"code,omitempty"`
+		Message string  `json:"message"`
+		Trace   []Frame `json:"trace"`
+		Cause   *Error  `json:"cause,omitempty"`
+	}{E.Code, E.Message(), E.Frames, E.Cause})
+}
+
+// withFrame returns a copy of the error with frame appended,
+// errors held by scripts are never modified.
+func (E *Error) withFrame(frame Frame) *Error {
+	traced := *E
+	if len(E.Frames) >= maxTraceFrames {
+		traced.omitted++
+		return &traced
+	}
+	traced.Frames = append(E.Frames[:len(E.Frames):len(E.Frames)], frame)
+	return &traced
+}
+
+// errorMessage describes a thrown value.
+func (R *Runtime) errorMessage(v Value) string {
+	switch value := v.(type) {
+	case String:
+		return string(value)
+	case *Error:
+		return value.Message()
+	case *Instance:
+		if R.getNativeField(v, NativeStr) != nil {
+			break
+		}
+		if message, err := R.getDynamicMember(v, String("message")); err == nil && message != nil {
+			if values, err := goNativeTypes(R, []Value{message}); err == nil {
+				return fmt.Sprint(values[0])
+			}
+		}
+		return string(v.Type())
+	}
+	str, err := R.Str(v)
+	if err != nil {
+		return err.Message()
+	}
+	return str
+}
@@ -2,7 +2,7 @@ package interpreter
 
 import "strconv"
 
-var floatMap = NativeMap{}
+var floatNatives = NativeMap{}
 
 func (Float) Type() String {
 	return "builtin+float"
@@ -25,16 +25,18 @@ func floatWrapBinary(f func(a, b float64) Value) Function {
 }
 
 func init() {
-	floatMap[NativeBool] = Function(func(r *Runtime, v []Value) (Value, *Error) { return Bool(v[0].(Float) != 0.0), nil })
-	floatMap[NativeStr] = Function(func(r *Runtime, v []Value) (Value, *Error) {
+	floatNatives[NativeBool] = Function(func(r *Runtime, v []Value) (Value, *Error) { return Bool(v[0].(Float) != 0.0), nil })
+	floatNatives[NativeStr] = Function(func(r *Runtime, v []Value) (Value, *Error) {
 		return String(strconv.FormatFloat(float64(v[0].(Float)), 'f', 7, 64)), nil
 	})
 
-	intNatives[NativeEq] = floatWrapBinary(func(a, b float64) Value { return Bool(a == b) })
-	intNatives[NativeLt] = floatWrapBinary(func(a, b float64) Value { return Bool(a < b) })
-	intNatives[NativeLe] = floatWrapBinary(func(a, b float64) Value { return Bool(a <= b) })
-	intNatives[NativeGt] = floatWrapBinary(func(a, b float64) Value { return Bool(a > b) })
-	intNatives[NativeGe] = floatWrapBinary(func(a, b float64) Value { return Bool(a >= b) })
+	floatNatives[NativeNeg] = floatWrapUnary(func(a float64) Value { return Float(-a) })
+
+	floatNatives[NativeEq] = floatWrapBinary(func(a, b float64) Value { return Bool(a == b) })
+	floatNatives[NativeLt] = floatWrapBinary(func(a, b float64) Value { return Bool(a < b) })
+	floatNatives[NativeLe] = floatWrapBinary(func(a, b float64) Value { return Bool(a <= b) })
+	floatNatives[NativeGt] = floatWrapBinary(func(a, b float64) Value { return Bool(a > b) })
+	floatNatives[NativeGe] = floatWrapBinary(func(a, b float64) Value { return Bool(a >= b) })
 
 	floatNatives[NativeAdd] = floatWrapBinary(floatAdd)
 	floatNatives[NativeSub] = floatWrapBinary(floatSub)
@@ -48,5 +50,5 @@ func floatMul(a, b float64) Value { return Float(a * b) }
 func floatDiv(a, b float64) Value { return Float(a / b) }
 
 func (Float) Natives() NativeMap {
-	return floatMap
+	return floatNatives
 }
@@ -4,10 +4,14 @@ import "github.com/worldOneo/rutist/ast"
 
 var funcdefNatives = NativeMap{}
 
+// FuncDef is a function or a block. A block runs in the scope
+// it was written in and a return inside it returns from the
+// function around it, a function runs in a new scope per call.
 type FuncDef struct {
-	args     []ast.Identifier
-	node     ast.Node
-	captured map[string]Value
+	args  []ast.Identifier
+	node  ast.Node
+	env   *Scope
+	block bool
 }
 
 func (FuncDef) Type() String {
@@ -25,17 +29,25 @@ func (FuncDef) Natives() NativeMap {
 }
 
 func (F *FuncDef) run(r *Runtime, v []Value) (Value, *Error) {
-	for k, v := range F.captured {
-		if r.GetVar(k) != nil {
-			continue
-		}
-		r.CurrentScope().variables[k] = v
+	if F.block {
+		defer r.enterScope(F.env)()
+		r.flow.loopBody = false
+		return r.Run(F.node)
 	}
+	scope := F.env.child()
 	for i := 0; i < len(F.args); i++ {
-		if i >= len(v) {
-			break
+		var arg Value
+		if i < len(v) {
+			arg = v[i]
 		}
-		r.CurrentScope().variables[F.args[i].Name] = v[i]
+		scope.variables[F.args[i].Name] = arg
+	}
+	loopBody := r.flow.loopBody
+	defer r.enterScope(scope)()
+	defer r.enterFunction()()
+	val, err := r.Run(F.node)
+	if err != nil && err.signal == signalReturn && !loopBody {
+		return err.Value, nil
 	}
-	return r.Run(F.node)
+	return val, err
 }
@@ -1,9 +1,9 @@
 package interpreter
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"path/filepath"
+	"io"
 
 	"github.com/worldOneo/rutist/ast"
 	"github.com/worldOneo/rutist/tokens"
@@ -13,8 +13,13 @@ var builtins = map[string]Function{}
 
 func init() {
 	builtins["print"] = builtinPrint
+	builtins["eprint"] = builtinEprint
+	builtins["readLine"] = builtinReadLine
+	builtins["readAll"] = builtinReadAll
 	builtins["try"] = builtinTrycatch
 	builtins["throw"] = builtinThrow
+	builtins["rethrow"] = builtinRethrow
+	builtins["finally"] = builtinFinally
 	builtins["run"] = builtinRun
 	builtins["str"] = builtinStr
 	builtins["module"] = builtinModule
@@ -23,8 +28,15 @@ func init() {
 	builtins["isNil"] = builtinIsNil
 	builtins["if"] = builtinIf
 	builtins["while"] = builtinWhile
+	builtins["for"] = builtinFor
+	builtins["range"] = builtinRange
+	builtins["stop"] = builtinStop
+	builtins["break"] = builtinBreak
+	builtins["continue"] = builtinContinue
+	builtins["return"] = builtinReturn
 	builtins["Map"] = func(r *Runtime, v []Value) (Value, *Error) { return Map{}, nil }
 	builtins["Dict"] = func(r *Runtime, v []Value) (Value, *Error) { return Dict{}, nil }
+	builtins["List"] = func(r *Runtime, v []Value) (Value, *Error) { return &List{append([]Value{}, v...)}, nil }
 }
 
 func builtinIsNil(r *Runtime, args []Value) (Value, *Error) {
@@ -49,31 +61,43 @@ func builtinImport(r *Runtime, args []Value) (Value, *Error) {
 	if !ok {
 		return builtinThrow(r, []Value{String("Import: Arg1 must be string")})
 	}
-	file := string(fileVar)
-	dir := filepath.Dir(r.File)
-	if !filepath.IsAbs(file) {
-		file = filepath.Join(dir, file)
+	module, ok := r.resolveImport(string(fileVar))
+	if !ok {
+		return builtinThrow(r, []Value{String(fmt.Sprintf("Import: Cannot find module %q", fileVar))})
+	}
+	file := module.name()
+	if exports, ok := r.modules.exports[file]; ok {
+		return exports, nil
 	}
-	content, e := ioutil.ReadFile(file)
+	if chain := r.modules.cycle(r.File, file); chain != nil {
+		return builtinThrow(r, []Value{String("Import: Circular import " + formatChain(chain))})
+	}
+	content, e := r.readModule(module)
 	if e != nil {
-		return nil, &Error{e}
+		return nil, &Error{Err: e}
 	}
 	code := string(content)
-	tokens, e := tokens.Lexer(code)
-	if err != nil {
-		return nil, &Error{e}
+	lexed, e := tokens.Lexer(code)
+	if syntax, ok := e.(*tokens.SyntaxError); ok {
+		syntax.File = file
 	}
-	parsed, e := ast.Parse(tokens, file)
-	if err != nil {
-		return nil, &Error{e}
+	if e != nil {
+		return nil, &Error{Err: e}
 	}
-
-	runtime := New(file)
-	_, err = runtime.Run(parsed)
-	if err != nil {
-		return nil, err
+	parsed, e := ast.Parse(lexed, file)
+	if e != nil {
+		return nil, &Error{Err: e}
 	}
-	return runtime.SpecialFields[String(SpecialfFieldExport)], nil
+
+	return r.modules.load(r.File, file, func() (Value, *Error) {
+		runtime := r.fork(file)
+		runtime.source = module
+		_, err := runtime.Run(parsed)
+		if err != nil {
+			return nil, &Error{Err: fmt.Errorf("Import: %s failed", fileVar), Cause: err}
+		}
+		return runtime.SpecialFields[String(SpecialfFieldExport)], nil
+	})
 }
 
 func builtinModule(r *Runtime, args []Value) (Value, *Error) {
@@ -118,6 +142,19 @@ func builtinStr(r *Runtime, args []Value) (Value, *Error) {
 	return strFunc(r, append([]Value{args[0]}, args...))
 }
 
+// Str converts a value to a Go string like the str builtin does.
+func (R *Runtime) Str(v Value) (string, *Error) {
+	str, err := builtinStr(R, []Value{v})
+	if err != nil {
+		return "", err
+	}
+	values, err := goNativeTypes(R, []Value{str})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(values[0]), nil
+}
+
 func builtinRun(r *Runtime, args []Value) (Value, *Error) {
 	if len(args) < 1 {
 		return builtinThrow(r, []Value{String("Run: Require at least 1 parameter")})
@@ -129,47 +166,123 @@ func builtinRun(r *Runtime, args []Value) (Value, *Error) {
 	return builtinThrow(r, []Value{String("Run: Param 1 must be runnable")})
 }
 
-func builtinThrow(_ *Runtime, args []Value) (Value, *Error) {
+func builtinThrow(r *Runtime, args []Value) (Value, *Error) {
 	if len(args) == 0 {
-		return nil, &Error{fmt.Errorf("")}
+		return nil, &Error{Err: fmt.Errorf("")}
+	}
+	value := args[0]
+	err := &Error{Err: errors.New(r.errorMessage(value)), Value: value}
+	if cause, ok := value.(*Error); ok {
+		err.Cause = cause
 	}
-	arg := goNativeTypes(args)
-	return nil, &Error{fmt.Errorf("%v", arg[0])}
+	return nil, err
 }
 
+func builtinRethrow(r *Runtime, args []Value) (Value, *Error) {
+	if len(args) != 1 {
+		return builtinThrow(r, []Value{String("Rethrow: Requires exactly 1 parameter")})
+	}
+	err, ok := args[0].(*Error)
+	if !ok {
+		return builtinThrow(r, []Value{String("Rethrow: Parameter 1 must be an error")})
+	}
+	return nil, err
+}
+
+// builtinTrycatch runs the body and passes a raised error to the first
+// handler whose class matches the thrown value:
+//
+//	try(body, ClassA, handlerA, ClassB, handlerB, catchAll, finally(block))
+//
+// Unhandled errors are raised again, without any handlers they are returned.
 func builtinTrycatch(r *Runtime, args []Value) (Value, *Error) {
 	if len(args) == 0 {
 		return nil, nil
 	}
+	var cleanup *finallyBlock
+	if last, ok := args[len(args)-1].(*finallyBlock); ok {
+		cleanup = last
+		args = args[:len(args)-1]
+	}
+	res, err := tryCatch(r, args)
+	if cleanup == nil {
+		return res, err
+	}
+	_, cleanupErr := r.invokeValue(cleanup.block, []Value{})
+	if cleanupErr != nil {
+		return nil, cleanupErr
+	}
+	return res, err
+}
 
-	if len(args) == 1 {
-		_, err := r.invokeValue(args[0], []Value{})
-		if err != nil {
-			return err, nil
-		}
+func tryCatch(r *Runtime, args []Value) (Value, *Error) {
+	_, err := r.invokeValue(args[0], []Value{})
+	if err == nil {
 		return nil, nil
 	}
+	if err.signal != signalNone {
+		return nil, err
+	}
+	handlers := args[1:]
+	if len(handlers) == 0 {
+		return err, nil
+	}
+	for i := 0; i < len(handlers); i += 2 {
+		if i == len(handlers)-1 {
+			return r.invokeValue(handlers[i], []Value{err})
+		}
+		class, ok := handlers[i].(Constructor)
+		if !ok {
+			return builtinThrow(r, []Value{String("Try: Handlers must be preceded by a class")})
+		}
+		if instanceOf(err.Value, class) {
+			return r.invokeValue(handlers[i+1], []Value{err})
+		}
+	}
+	return nil, err
+}
 
-	_, err := r.invokeValue(args[0], []Value{})
-	if err != nil {
-		return r.invokeValue(args[1], []Value{err})
+type finallyBlock struct {
+	block Value
+}
+
+func (*finallyBlock) Type() String {
+	return "builtin+finally"
+}
+
+var finallyNatives = NativeMap{}
+
+func (*finallyBlock) Natives() NativeMap {
+	return finallyNatives
+}
+
+func builtinFinally(r *Runtime, args []Value) (Value, *Error) {
+	if len(args) != 1 {
+		return builtinThrow(r, []Value{String("Finally: Requires exactly 1 parameter")})
 	}
-	return nil, nil
+	return &finallyBlock{args[0]}, nil
+}
+
+func builtinPrint(r *Runtime, args []Value) (Value, *Error) {
+	return fprint(r, r.Stdout, args)
 }
 
-func builtinPrint(_ *Runtime, args []Value) (Value, *Error) {
+func fprint(r *Runtime, w io.Writer, args []Value) (Value, *Error) {
 	if len(args) == 0 {
-		fmt.Println()
+		fmt.Fprintln(w)
 		return nil, nil
 	}
-	values := goNativeTypes(args)
+	values, err := goNativeTypes(r, args)
+	if err != nil {
+		return nil, err
+	}
 
 	str, ok := values[0].(string)
 	if !ok {
-		fmt.Print(values...)
+		fmt.Fprint(w, values...)
 		return nil, nil
 	}
-	fmt.Printf(str, values[1:]...)
+	fmt.Fprintf(w, str, values[1:]...)
 	return nil, nil
 }
 
@@ -179,7 +292,8 @@ func createTrashCan(res Value) Function {
 	}
 }
 
-func goNativeTypes(args []Value) []interface{} {
+// goNativeTypes converts values for formatting, lists are formatted by str.
+func goNativeTypes(r *Runtime, args []Value) ([]interface{}, *Error) {
 	values := make([]interface{}, len(args))
 	for i, arg := range args {
 		switch value := arg.(type) {
@@ -193,9 +307,17 @@ func goNativeTypes(args []Value) []interface{} {
 			values[i] = bool(value)
 		case *Error:
 			values[i] = value.Err
+		case GoValue:
+			values[i] = value.Interface()
+		case *List:
+			str, err := listStr(r, []Value{value})
+			if err != nil {
+				return nil, err
+			}
+			values[i] = string(str.(String))
 		default:
 			values[i] = value
 		}
 	}
-	return values
+	return values, nil
 }
@@ -16,7 +16,7 @@ func intWrapBinary(f func(a, b int) Value, floatBinary func(a, b float64) Value)
 		if b, ok := v[1].(Int); ok {
 			return f(int(a), int(b)), nil
 		}
-		if b, ok := v[1].(Float); ok {
+		if b, ok := v[1].(Float); ok && floatBinary != nil {
 			return floatBinary(float64(a), float64(b)), nil
 		}
 		return builtinThrow(r, []Value{String("Invalid right hand value")})
@@ -32,6 +32,7 @@ func intWrapUnary(f func(a int) Value) Function {
 func init() {
 	intNatives[NativeBool] = intWrapUnary(func(a int) Value { return Bool(a != 0) })
 	intNatives[NativeNot] = intWrapUnary(func(a int) Value { return Bool(a == 0) })
+	intNatives[NativeNeg] = intWrapUnary(func(a int) Value { return Int(-a) })
 
 	intNatives[NativeStr] = intWrapUnary(func(a int) Value { return String(strconv.Itoa(a)) })
 
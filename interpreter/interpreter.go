@@ -1,40 +1,106 @@
 package interpreter
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
 
 	"github.com/worldOneo/rutist/ast"
 )
 
 type Runtime struct {
 	File          string
-	Scopes        []*Scope
-	ScopeIndex    int
+	Path          []string
+	Mounts        map[string]fs.FS
+	Module        *Scope
+	scope         *Scope
 	SpecialFields Map
+	Globals       Locals
+	Stdout        io.Writer
+	Stderr        io.Writer
+	Stdin         io.Reader
+	MaxSteps      int
+	MaxDepth      int
+	Timeout       time.Duration
+	input         *input
+	budget        *budget
+	modules       *modules
+	source        moduleFile
+	flow          flow
 }
 
 const (
 	SpecialfFieldExport = String("export")
 )
 
+// DefaultMaxDepth limits the call depth of new runtimes,
+// so deep recursion raises an error instead of exhausting the Go stack.
+const DefaultMaxDepth = 10000
+
+// maxTraceFrames limits the frames recorded in the trace of an error.
+const maxTraceFrames = 64
+
 func New(file string) *Runtime {
+	module := NewScope()
 	return &Runtime{
-		file,
-		[]*Scope{NewScope()},
-		0,
-		map[Value]Value{
+		File:   file,
+		Module: module,
+		scope:  module,
+		SpecialFields: map[Value]Value{
 			SpecialfFieldExport: Dict{},
 		},
+		Globals:  Locals{},
+		Mounts:   map[string]fs.FS{},
+		Stdout:   os.Stdout,
+		Stderr:   os.Stderr,
+		Stdin:    os.Stdin,
+		MaxDepth: DefaultMaxDepth,
+		input:    &input{},
+		budget:   &budget{},
+		modules:  newModules(),
 	}
 }
 
+// fork creates the runtime for another file which shares
+// the configuration of this runtime.
+func (R *Runtime) fork(file string) *Runtime {
+	runtime := New(file)
+	runtime.Globals = R.Globals
+	runtime.Path = R.Path
+	runtime.Mounts = R.Mounts
+	runtime.Stdout = R.Stdout
+	runtime.Stderr = R.Stderr
+	runtime.Stdin = R.Stdin
+	runtime.MaxSteps = R.MaxSteps
+	runtime.MaxDepth = R.MaxDepth
+	runtime.Timeout = R.Timeout
+	runtime.input = R.input
+	runtime.budget = R.budget
+	runtime.modules = R.modules
+	return runtime
+}
+
+// RegisterBuiltin defines a builtin for this runtime only,
+// shadowing the default builtin with the same name.
+func (R *Runtime) RegisterBuiltin(name string, function Function) {
+	R.Globals[name] = function
+}
+
+// RemoveBuiltin hides the builtin name from this runtime.
+func (R *Runtime) RemoveBuiltin(name string) {
+	R.Globals[name] = nil
+}
+
 func Run(file string, ast ast.Node) (Value, error) {
 	runtime := New(file)
 	val, err := runtime.Run(ast)
 	if err == nil {
 		return val, nil
 	}
-	return val, err.Err
+	return val, err
 }
 
 func NewScope() *Scope {
@@ -50,6 +116,9 @@ func (R *Runtime) Run(program ast.Node) (Value, *Error) {
 		var lastVal Value
 		var err *Error
 		for i := 0; i < len(node.Body); i++ {
+			if err = R.step(); err != nil {
+				return nil, R.bindTrace(err, node.Body[i])
+			}
 			lastVal, err = R.Run(node.Body[i])
 			if err != nil {
 				return nil, R.bindTrace(err, node)
@@ -61,22 +130,10 @@ func (R *Runtime) Run(program ast.Node) (Value, *Error) {
 	case ast.Expression:
 		return R.invokeExpression(node)
 	case ast.Assignment:
-		identifier, ok := node.Identifier.(ast.Identifier)
-		lazy := Lazy()
-		if ok {
-			current := R.GetVar(identifier.Name)
-			if current != nil {
-				lazy.WakeUp(current)
-			}
-			R.CurrentScope().variables[identifier.Name] = lazy
-		}
 		val, err := R.Run(node.Value)
 		if err != nil {
 			return nil, R.bindTrace(err, node)
 		}
-		if ok {
-			lazy.WakeUp(val)
-		}
 		return R.assignValue(val, node.Identifier)
 	case ast.BinaryExpression:
 		left, err := R.Run(node.Left)
@@ -89,13 +146,17 @@ func (R *Runtime) Run(program ast.Node) (Value, *Error) {
 		}
 		operator := R.getNativeField(left, operatorMagicType[node.Operation])
 		if operator == nil {
-			return nil, R.error("Invalid operator", node)
+			return nil, R.error("E201", "Invalid operator", node)
 		}
 		fn, ok := operator.(Function)
 		if !ok {
-			return nil, R.error("Invalid operator", node)
+			return nil, R.error("E201", "Invalid operator", node)
+		}
+		res, err := R.CallFunction(fn, []Value{left, right})
+		if err != nil {
+			return nil, R.bindTrace(err, node)
 		}
-		return R.CallFunction(fn, []Value{left, right})
+		return res, nil
 	case ast.UnaryExpression:
 		val, err := R.Run(node.Value)
 		if err != nil {
@@ -103,13 +164,17 @@ func (R *Runtime) Run(program ast.Node) (Value, *Error) {
 		}
 		operator := R.getNativeField(val, operatorMagicType[node.Operation])
 		if operator == nil {
-			return nil, R.error("Invalid operator", node)
+			return nil, R.error("E201", "Invalid operator", node)
 		}
 		fn, ok := operator.(Function)
 		if !ok {
-			return nil, R.error("Invalid operator", node)
+			return nil, R.error("E201", "Invalid operator", node)
 		}
-		return R.CallFunction(fn, []Value{val})
+		res, err := R.CallFunction(fn, []Value{val})
+		if err != nil {
+			return nil, R.bindTrace(err, node)
+		}
+		return res, nil
 	case ast.Identifier:
 		return R.GetVar(node.Name), nil
 	case ast.Float:
@@ -121,62 +186,126 @@ func (R *Runtime) Run(program ast.Node) (Value, *Error) {
 	case ast.String:
 		return String(node.Value), nil
 	case ast.Scope:
-		return &FuncDef{[]ast.Identifier{}, node.Body, R.CopyLocals()}, nil
+		return &FuncDef{[]ast.Identifier{}, node.Body, R.scope, true}, nil
 	case ast.FunctionDefinition:
-		return &FuncDef{node.ArgList, node.Scope, R.CopyLocals()}, nil
+		return &FuncDef{node.ArgList, node.Scope, R.scope, false}, nil
 	case ast.MemberSelector:
 		return R.resolveMemberSelector(node)
+	case ast.Group:
+		return R.Run(node.Value)
+	case ast.ListLiteral:
+		values := make([]Value, len(node.Values))
+		for i, value := range node.Values {
+			val, err := R.Run(value)
+			if err != nil {
+				return nil, R.bindTrace(err, node)
+			}
+			values[i] = val
+		}
+		return &List{values}, nil
+	case ast.Index:
+		obj, err := R.Run(node.Object)
+		if err != nil {
+			return nil, R.bindTrace(err, node)
+		}
+		index, err := R.Run(node.Index)
+		if err != nil {
+			return nil, R.bindTrace(err, node)
+		}
+		return R.index(NativeGetIndex, node, obj, index)
+	case ast.MapLiteral:
+		m := Map{}
+		for _, entry := range node.Entries {
+			key, err := R.Run(entry.Key)
+			if err != nil {
+				return nil, R.bindTrace(err, node)
+			}
+			if !hashable(key) {
+				return nil, R.error("E204", fmt.Sprintf("Map: Key of type %s is not hashable", typeName(key)), entry.Key)
+			}
+			val, err := R.Run(entry.Value)
+			if err != nil {
+				return nil, R.bindTrace(err, node)
+			}
+			m[key] = val
+		}
+		return m, nil
+	case ast.DictLiteral:
+		d := Dict{}
+		for _, entry := range node.Entries {
+			val, err := R.Run(entry.Value)
+			if err != nil {
+				return nil, R.bindTrace(err, node)
+			}
+			d[String(entry.Key.(ast.Identifier).Name)] = val
+		}
+		return d, nil
+	case ast.ErrorNode:
+		return nil, R.error(node.Err.Code, node.Err.Message, node)
 	}
 	return nil, nil
 }
 
 func (R *Runtime) GetVar(name string) Value {
-	v, ok := R.CurrentScope().variables[name]
+	_, v, ok := R.scope.lookup(name)
+	if !ok {
+		v, ok = R.Globals[name]
+	}
 	if !ok {
 		v, ok = builtins[name]
 		if !ok {
 			return nil
 		}
 	}
-	if lazy, ok := v.(*LazyObject); ok {
-		return lazy.Resolve()
-	}
 	return v
 }
 
-func (R *Runtime) CurrentScope() *Scope {
-	return R.Scopes[R.ScopeIndex]
+func (R *Runtime) SetVar(name string, value Value) {
+	R.scope.variables[name] = value
 }
 
-func (R *Runtime) CopyLocals() Locals {
-	new := make(Locals)
-	for k, v := range R.CurrentScope().variables {
-		new[k] = v
-	}
-	return new
+func (R *Runtime) CurrentScope() *Scope {
+	return R.scope
 }
 
-func (R *Runtime) raiseScope() {
-	R.ScopeIndex++
-	if R.ScopeIndex >= len(R.Scopes) {
-		R.Scopes = append(R.Scopes, nil)
-	}
-	R.Scopes[R.ScopeIndex] = NewScope()
+// enterScope runs the following code in scope,
+// the returned func restores the previous scope.
+func (R *Runtime) enterScope(scope *Scope) func() {
+	previous := R.scope
+	R.scope = scope
+	return func() { R.scope = previous }
 }
 
-func (R *Runtime) lowerScope() {
-	R.Scopes[R.ScopeIndex] = nil
-	R.ScopeIndex--
+func (R *Runtime) CallFunction(function Function, args []Value) (val Value, err *Error) {
+	if err = R.step(); err != nil {
+		return nil, err
+	}
+	if R.MaxDepth > 0 && R.budget.depth >= R.MaxDepth {
+		return nil, &Error{Code: "E208", Err: fmt.Errorf("Stack overflow: call depth limit of %d exceeded", R.MaxDepth)}
+	}
+	R.budget.depth++
+	defer R.recoverNative(R.scope, R.budget.depth-1, &err)
+	val, err = function(R, args)
+	R.budget.depth--
+	return val, err
 }
 
-func (R *Runtime) CallFunction(function Function, args []Value) (Value, *Error) {
-	R.raiseScope()
-	val, err := function(R, args)
-	R.lowerScope()
-	return val, err
+// recoverNative converts a panic of a native function into an error
+// and restores the scopes and call depth from before the call.
+func (R *Runtime) recoverNative(scope *Scope, depth int, err **Error) {
+	p := recover()
+	if p == nil {
+		return
+	}
+	R.scope = scope
+	R.budget.depth = depth
+	*err = &Error{Code: "E211", Err: fmt.Errorf("Panic: %v", p)}
 }
 
 func (R *Runtime) getNativeField(v Value, field int) Value {
+	if v == nil {
+		return nil
+	}
 	n := v.Natives()[field]
 	return n
 }
@@ -188,9 +317,9 @@ func (R *Runtime) getDynamicMember(v Value, property Value) (Value, *Error) {
 	}
 	f, ok := getMember.(Function)
 	if !ok {
-		return nil, &Error{fmt.Errorf("Invalid member")}
+		return nil, &Error{Code: "E207", Err: fmt.Errorf("Invalid member")}
 	}
-	return f(R, []Value{v, property})
+	return R.CallFunction(f, []Value{v, property})
 }
 
 func (R *Runtime) getMember(v Value, field String) (Value, *Error) {
@@ -211,7 +340,7 @@ func (R *Runtime) getMember(v Value, field String) (Value, *Error) {
 
 func (R *Runtime) getMemberProperty(v Value, property ast.Node) (Value, *Error) {
 	if v == nil {
-		return nil, R.error("Member: value is nil", property)
+		return nil, R.error("E207", "Member: value is nil", property)
 	}
 	switch prop := property.(type) {
 	case ast.Identifier:
@@ -230,7 +359,7 @@ func (R *Runtime) getMemberProperty(v Value, property ast.Node) (Value, *Error)
 		}
 		return R.getMemberProperty(member, prop.Property)
 	}
-	return nil, R.error("Invalid property", property)
+	return nil, R.error("E207", "Invalid property", property)
 }
 
 func (R *Runtime) buildArgs(this Value, args []ast.Node) ([]Value, *Error) {
@@ -257,31 +386,39 @@ func (R *Runtime) invokeExpression(node ast.Expression) (Value, *Error) {
 	}
 	runnable := R.getNativeField(value, NativeRun)
 	if runnable == nil {
-		return nil, R.error("Invalid invocation", node)
+		return nil, R.error("E202", "Invalid invocation", node)
 	}
 	function, ok := runnable.(Function)
 	if !ok {
-		return nil, R.error("Invalid invocation", node)
+		return nil, R.error("E202", "Invalid invocation", node)
 	}
 	args, err := R.buildArgs(value, node.ArgList)
 	if err != nil {
 		return nil, R.bindTrace(err, node)
 	}
-	return R.invokeFunction(function, args)
+	val, err := R.invokeFunction(function, args)
+	if err != nil {
+		return nil, R.bindCall(err, node)
+	}
+	return val, nil
 }
 
 func (R *Runtime) invokeValue(value Value, args []Value) (Value, *Error) {
 	runnable := R.getNativeField(value, NativeRun)
 	if runnable == nil {
-		return nil, &Error{fmt.Errorf("Invalid invocation")}
+		return nil, &Error{Code: "E202", Err: fmt.Errorf("Invalid invocation")}
 	}
 	function, ok := runnable.(Function)
 	if !ok {
-		return nil, &Error{fmt.Errorf("Invalid invocation")}
+		return nil, &Error{Code: "E202", Err: fmt.Errorf("Invalid invocation")}
 	}
 	return R.invokeFunction(function, append([]Value{value}, args...))
 }
 
+func (R *Runtime) Invoke(value Value, args []Value) (Value, *Error) {
+	return R.invokeValue(value, args)
+}
+
 func (R *Runtime) resolveMemberSelector(node ast.MemberSelector) (Value, *Error) {
 	v, err := R.Run(node.Object)
 	if err != nil {
@@ -293,7 +430,17 @@ func (R *Runtime) resolveMemberSelector(node ast.MemberSelector) (Value, *Error)
 func (R *Runtime) assignValue(val Value, node ast.Node) (Value, *Error) {
 	switch v := node.(type) {
 	case ast.Identifier:
-		R.CurrentScope().variables[v.Name] = val
+		R.scope.variables[v.Name] = val
+		return nil, nil
+	case ast.ScopedIdentifier:
+		scope := R.scope.root()
+		if !v.Module {
+			var ok bool
+			if scope, _, ok = R.scope.lookup(v.Name); !ok {
+				return nil, R.error("E206", fmt.Sprintf("Assign: @%s is not defined", v.Name), node)
+			}
+		}
+		scope.variables[v.Name] = val
 		return nil, nil
 	case ast.MemberSelector:
 		obj, err := R.Run(v.Object)
@@ -305,8 +452,32 @@ func (R *Runtime) assignValue(val Value, node ast.Node) (Value, *Error) {
 			return R.assignObjectProperty(obj, val, v.Property)
 		}
 		return R.assignObject(obj, val, String(prop.Name))
+	case ast.Index:
+		obj, err := R.Run(v.Object)
+		if err != nil {
+			return nil, R.bindTrace(err, node)
+		}
+		index, err := R.Run(v.Index)
+		if err != nil {
+			return nil, R.bindTrace(err, node)
+		}
+		_, err = R.index(NativeSetIndex, v, obj, index, val)
+		return nil, err
+	}
+	return nil, R.error("E203", "Invalid assignment", node)
+}
+
+// index calls the get or set index native of obj.
+func (R *Runtime) index(native int, node ast.Index, obj Value, args ...Value) (Value, *Error) {
+	fn, ok := R.getNativeField(obj, native).(Function)
+	if !ok {
+		return nil, R.error("E205", fmt.Sprintf("Index: %s can't be indexed", typeName(obj)), node)
+	}
+	res, err := R.CallFunction(fn, append([]Value{obj}, args...))
+	if err != nil {
+		return nil, R.bindTrace(err, node)
 	}
-	return nil, R.error("Invalid assignment", node)
+	return res, nil
 }
 
 func (R *Runtime) assignObjectProperty(obj Value, val Value, node ast.Node) (Value, *Error) {
@@ -316,7 +487,7 @@ func (R *Runtime) assignObjectProperty(obj Value, val Value, node ast.Node) (Val
 	case ast.MemberSelector:
 		key, ok := v.Object.(ast.Identifier)
 		if !ok {
-			return nil, R.error("Invalid assignment", node)
+			return nil, R.error("E203", "Invalid assignment", node)
 		}
 		member, err := R.getDynamicMember(obj, String(key.Name))
 		if err != nil {
@@ -328,29 +499,66 @@ func (R *Runtime) assignObjectProperty(obj Value, val Value, node ast.Node) (Val
 		}
 		return R.assignObject(member, val, String(prop.Name))
 	}
-	return nil, R.error("Invalid assignment", node)
+	return nil, R.error("E203", "Invalid assignment", node)
 }
 
 func (R *Runtime) assignObject(obj Value, val Value, prop Value) (Value, *Error) {
 	assign := R.getNativeField(obj, NativeSetMember)
 	if assign == nil {
-		return nil, &Error{fmt.Errorf("Invalid assignment")}
+		return nil, &Error{Code: "E203", Err: fmt.Errorf("Invalid assignment")}
 	}
 	assignFn := R.getNativeField(assign, NativeRun)
 	if assignFn == nil {
-		return nil, &Error{fmt.Errorf("Invalid assignment")}
+		return nil, &Error{Code: "E203", Err: fmt.Errorf("Invalid assignment")}
 	}
 	fn, ok := assignFn.(Function)
 	if !ok {
-		return nil, &Error{fmt.Errorf("Invalid assignment")}
+		return nil, &Error{Code: "E203", Err: fmt.Errorf("Invalid assignment")}
 	}
 	return R.CallFunction(fn, []Value{assignFn, assign, obj, prop, val})
 }
 
+// bindTrace locates an error which was raised without a location.
 func (R *Runtime) bindTrace(err *Error, node ast.Node) *Error {
-	return R.error(err.Err.Error(), node)
+	if len(err.Frames) > 0 || err.omitted > 0 {
+		return err
+	}
+	return err.withFrame(frameAt(node, ""))
 }
 
-func (R *Runtime) error(msg string, node ast.Node) *Error {
-	return &Error{fmt.Errorf("%s\n\tat %s:%d", msg, node.File(), node.Token().Line+1)}
+// bindCall records the call of node in the trace of an error.
+func (R *Runtime) bindCall(err *Error, node ast.Expression) *Error {
+	return err.withFrame(frameAt(node, nodeName(node.Callee)))
+}
+
+// error raises the interpreter error code at node.
+func (R *Runtime) error(code string, msg string, node ast.Node) *Error {
+	return &Error{Code: code, Err: errors.New(msg), Frames: []Frame{frameAt(node, "")}}
+}
+
+// frameAt locates node, binary expressions are located at their operator.
+func frameAt(node ast.Node, function string) Frame {
+	start := node.Span().Start
+	if _, binary := node.(ast.BinaryExpression); binary || start.Line == 0 {
+		start = node.Token().Start
+	}
+	return Frame{
+		File:     node.File(),
+		Line:     start.Line,
+		Column:   start.Column,
+		Function: function,
+	}
+}
+
+// nodeName names a callee for traces.
+func nodeName(node ast.Node) string {
+	switch n := node.(type) {
+	case ast.Identifier:
+		return n.Name
+	case ast.MemberSelector:
+		return nodeName(n.Object) + "." + nodeName(n.Property)
+	case ast.Expression:
+		return nodeName(n.Callee) + "()"
+	}
+	return "<anonymous>"
 }
@@ -1,8 +1,14 @@
 package interpreter
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/worldOneo/rutist/ast"
 	"github.com/worldOneo/rutist/tokens"
@@ -41,8 +47,7 @@ func TestRun_Status(t *testing.T) {
 			},
 			func(r *Runtime) bool {
 				v := r.GetVar("err").(*Error)
-				_, err := builtinThrow(r, []Value{String("This is an error\n\tat constant.go:4")})
-				return v.Err.Error() == err.Err.Error()
+				return v.Error() == "This is an error\n\tat throw (constant.go:3:6)"
 			},
 			false,
 		},
@@ -90,7 +95,7 @@ func TestRun_Status(t *testing.T) {
 				if !o {
 					return false
 				}
-				return reflect.DeepEqual(h.args, []ast.Identifier{{"err", ast.NewMeta(tokens.Token{tokens.Identifier, "err", 0, 0, 1}, "constant.go")}})
+				return reflect.DeepEqual(h.args, []ast.Identifier{{Name: "err", Meta: ast.NewMeta(tokens.Token{Type: tokens.Identifier, Content: "err", Start: tokens.Position{Offset: 15, Line: 2, Column: 15}, End: tokens.Position{Offset: 18, Line: 2, Column: 18}}, "constant.go")}})
 			},
 			false,
 		},
@@ -231,8 +236,28 @@ func TestRun_Status(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"error members",
+			args{ast.Parsep(tokens.Lexerp(`
+			fail = () {
+				throw("inner")
+			}
+			err = try({ fail() })
+			message = err.message
+			top = err.trace.get(0)
+			caller = err.trace.get(1)
+			`))},
+			func(r *Runtime) bool {
+				top, _ := r.GetVar("top").(Dict)
+				caller, _ := r.GetVar("caller").(Dict)
+				return r.GetVar("message") == String("inner") &&
+					top[String("function")] == String("throw") && top[String("line")] == Int(3) &&
+					caller[String("function")] == String("fail") && caller[String("line")] == Int(5)
+			},
+			false,
+		},
 	}
-	for _, tt := range tests[11:] {
+	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r := New("test.go")
 			_, err := r.Run(tt.args.ast)
@@ -315,6 +340,430 @@ func TestRun_Abstract(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"Typed exceptions",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					ValidationError = class((def) {
+						def("__init__", (self, field) { self.field = field })
+					})
+					IOError = class((def) {})
+					state = Dict()
+
+					a = try({ throw(ValidationError("name")) }, IOError, (e) { "io" }, ValidationError, (e) { e.field })
+					b = try({
+						try({ throw(IOError()) }, ValidationError, (e) { "validation" }, finally({ state.cleaned = true }))
+					}, IOError, (e) { "io" })
+					c = try({
+						try({ throw("inner") }, (e) { rethrow(e) })
+					}, (e) { e.message })
+					d = try({ throw(ValidationError("age")) }).value.field
+					e = try({ throw(ValidationError("age")) }).message
+				`)),
+			},
+			func(r *Runtime) bool {
+				state := r.GetVar("state").(Dict)
+				return r.GetVar("a") == String("name") &&
+					r.GetVar("b") == String("io") &&
+					state[String("cleaned")] == Bool(true) &&
+					r.GetVar("c") == String("inner") &&
+					r.GetVar("d") == String("age") &&
+					r.GetVar("e") == String("class")
+			},
+			false,
+		},
+		{
+			"Arithmetic",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					a = 10 - 4 - 3
+					b = 1 + 2 * 3 == 7
+					c = true || false && false
+					d = -2 * 3
+					e = 1.5 * 2.0 < 3.5
+					f = 6 ^ 3 | 8
+					g = !true || 2 << 1 == 4
+					h = (1 + 2) * -(4 - 1)
+				`)),
+			},
+			func(r *Runtime) bool {
+				return r.GetVar("a") == Int(3) &&
+					r.GetVar("b") == Bool(true) &&
+					r.GetVar("c") == Bool(true) &&
+					r.GetVar("d") == Int(-6) &&
+					r.GetVar("e") == Bool(true) &&
+					r.GetVar("f") == Int(13) &&
+					r.GetVar("g") == Bool(true) &&
+					r.GetVar("h") == Int(-9)
+			},
+			false,
+		},
+		{
+			"Lists",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					l = [3, 1, 2]
+					l.push(5, 4)
+					l.sort()
+					last = l.pop()
+					l.insert(0, "first")
+					removed = l.remove(1)
+					found = l.index(3)
+					has = l.contains(9)
+					part = l.slice(1, -1)
+					part.reverse()
+					text = str(part)
+					eq = [1, [2]] == [1, [2]]
+					words = ["bb", "a", "ccc"]
+					words.sort((a, b) { a.len() < b.len() })
+					shortest = words.get(0)
+				`)),
+			},
+			func(r *Runtime) bool {
+				return r.GetVar("last") == Int(5) &&
+					r.GetVar("removed") == Int(1) &&
+					r.GetVar("found") == Int(2) &&
+					r.GetVar("has") == Bool(false) &&
+					r.GetVar("text") == String("[3, 2]") &&
+					r.GetVar("eq") == Bool(true) &&
+					r.GetVar("shortest") == String("a")
+			},
+			false,
+		},
+		{
+			"Lists with mixed types",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					l = [1, "a", 2.5, nil]
+					hasA = l.contains("a")
+					hasFloat = l.contains(2.5)
+					hasB = l.contains("b")
+					found = l.index(nil)
+					missing = l.index(1.0)
+					eq = [1] == [1.0]
+					names = ["b", "c", "a"]
+					names.sort()
+					first = names.get(0)
+				`)),
+			},
+			func(r *Runtime) bool {
+				return r.GetVar("hasA") == Bool(true) &&
+					r.GetVar("hasFloat") == Bool(true) &&
+					r.GetVar("hasB") == Bool(false) &&
+					r.GetVar("found") == Int(3) &&
+					r.GetVar("missing") == Int(-1) &&
+					r.GetVar("eq") == Bool(false) &&
+					r.GetVar("first") == String("a")
+			},
+			false,
+		},
+		{
+			"Map and Dict literals",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					m = #{"a": 1, 2: "b", "c": [1, 2]}
+					config = {
+						.name = "server",
+						.port = 80 * 100,
+					}
+					a = m.get("a")
+					b = m.get(2)
+					c = m.get("c").len()
+					name = config.name
+					port = config.port
+				`)),
+			},
+			func(r *Runtime) bool {
+				return r.GetVar("a") == Int(1) &&
+					r.GetVar("b") == String("b") &&
+					r.GetVar("c") == Int(2) &&
+					r.GetVar("name") == String("server") &&
+					r.GetVar("port") == Int(8000)
+			},
+			false,
+		},
+		{
+			"Index",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					l = [1, 2, 3]
+					l[0] = 10
+					m = #{"a": 1}
+					m["b"] = l[-1]
+					d = {.name = "x"}
+					d["age"] = 3
+					grid = [[1, 2], [3, 4]]
+					grid[1][0] = 7
+					vec = class((def) {
+						def("__init__", (self) { self.items = [] })
+						def("__getindex__", (self, i) { self.items[i] * 2 })
+						def("__setindex__", (self, i, v) { self.items.insert(i, v) })
+					})
+					v = vec()
+					v[0] = 21
+					a = l[0]
+					b = m["b"]
+					c = d.age
+					s = "héllo"[1]
+					g = grid[1][0]
+					i = v[0]
+				`)),
+			},
+			func(r *Runtime) bool {
+				return r.GetVar("a") == Int(10) &&
+					r.GetVar("b") == Int(3) &&
+					r.GetVar("c") == Int(3) &&
+					r.GetVar("s") == String("é") &&
+					r.GetVar("g") == Int(7) &&
+					r.GetVar("i") == Int(42)
+			},
+			false,
+		},
+		{
+			"Index not supported",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					1[0]
+				`)),
+			},
+			func(r *Runtime) bool { return true },
+			true,
+		},
+		{
+			"Iteration",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					seen = []
+					for([1, 2], (x) { seen.push(x) })
+					for(range(3), (i) { seen.push(i) })
+					for(range(10, 0, -4), (i) { seen.push(i) })
+					for("hé", (c) { seen.push(c) })
+					for(#{"b": 2, "a": 1}, (k) { seen.push(k) })
+					for({.y = 1, .x = 2}, (k) { seen.push(k) })
+					countdown = class((def) {
+						def("__init__", (self, n) { self.n = n })
+						def("__iter__", (self) { self })
+						def("__next__", (self) {
+							if({ self.n == 0 }, { stop() }).else({
+								self.n = self.n - 1
+								self.n + 1
+							}).value
+						})
+					})
+					for(countdown(2), (i) { seen.push(i) })
+					text = str(seen)
+				`)),
+			},
+			func(r *Runtime) bool {
+				return r.GetVar("text") == String(`[1, 2, 0, 1, 2, 10, 6, 2, "h", "é", "a", "b", "x", "y", 2, 1]`)
+			},
+			false,
+		},
+		{
+			"Closures",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					makeCounter = () {
+						n = 0
+						{
+							n = n + 1
+							n
+						}
+					}
+					counter = makeCounter()
+					counter()
+					a = counter()
+					b = makeCounter()()
+					later = () { defined }
+					defined = 5
+					c = later()
+					fib = (n) {
+						if({ n < 2 }, { n }).else({ fib(n - 1) + fib(n - 2) }).value
+					}
+					d = fib(10)
+					x = 1
+					shadow = (x) { x = 3 }
+					shadow(2)
+					local = () { x = 4 }
+					local()
+				`)),
+			},
+			func(r *Runtime) bool {
+				return r.GetVar("a") == Int(2) &&
+					r.GetVar("b") == Int(1) &&
+					r.GetVar("c") == Int(5) &&
+					r.GetVar("d") == Int(55) &&
+					r.GetVar("x") == Int(1)
+			},
+			false,
+		},
+		{
+			"Scoped assignment",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					total = 0
+					for([1, 2, 3], (i) { @total = total + i })
+					makeCounter = () {
+						n = 0
+						inc = () {
+							@n = n + 1
+							n
+						}
+						inc
+					}
+					counter = makeCounter()
+					counter()
+					a = counter()
+					setGlobal = () {
+						total = 0
+						reset = () { @@total = 10 }
+						reset()
+						total
+					}
+					b = setGlobal()
+				`)),
+			},
+			func(r *Runtime) bool {
+				return r.GetVar("total") == Int(10) &&
+					r.GetVar("a") == Int(2) &&
+					r.GetVar("b") == Int(0)
+			},
+			false,
+		},
+		{
+			"Scoped assignment of undefined",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					f = () { @missing = 1 }
+					f()
+				`)),
+			},
+			func(r *Runtime) bool { return true },
+			true,
+		},
+		{
+			"Control flow",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					seen = []
+					n = Map()
+					n.set("i", 0)
+					while({ true }, {
+						n.set("i", n.get("i") + 1)
+						if({ n.get("i") == 2 }, { continue() })
+						if({ n.get("i") > 4 }, { break() })
+						seen.push(n.get("i"))
+					})
+					for(range(6), (i) {
+						if({ i % 2 == 0 }, { continue() })
+						if({ i == 5 }, { break() })
+						seen.push(i)
+					})
+					first = (l) {
+						if({ l.len() > 0 }, { return(l[0]) })
+						return()
+					}
+					guarded = () {
+						try({ return("early") })
+						"late"
+					}
+					search = () {
+						for(range(5), (i) {
+							if({ i == 2 }, { return("found") })
+						})
+						"notfound"
+					}
+					a = first([7])
+					b = first([])
+					c = guarded()
+					d = search()
+					text = str(seen)
+				`)),
+			},
+			func(r *Runtime) bool {
+				return r.GetVar("text") == String("[1, 3, 4, 1, 3]") &&
+					r.GetVar("a") == Int(7) &&
+					r.GetVar("b") == nil &&
+					r.GetVar("c") == String("early") &&
+					r.GetVar("d") == String("found")
+			},
+			false,
+		},
+		{
+			"Break outside of a loop",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					f = () { break() }
+					while({ true }, { f() })
+				`)),
+			},
+			func(r *Runtime) bool { return true },
+			true,
+		},
+		{
+			"Return outside of a function",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					if({ true }, { return(1) })
+				`)),
+			},
+			func(r *Runtime) bool { return true },
+			true,
+		},
+		{
+			"Return in a loop outside of a function",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					for(range(3), (i) { return(i) })
+				`)),
+			},
+			func(r *Runtime) bool { return true },
+			true,
+		},
+		{
+			"Not iterable",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					for(1, (x) {})
+				`)),
+			},
+			func(r *Runtime) bool { return true },
+			true,
+		},
+		{
+			"Unhashable map key",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					#{Map(): 1}
+				`)),
+			},
+			func(r *Runtime) bool { return true },
+			true,
+		},
+		{
+			"Unhashable dict index",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					d = Dict()
+					err = try({ d[#{}] })
+				`)),
+			},
+			func(r *Runtime) bool {
+				err, ok := r.GetVar("err").(*Error)
+				return ok && err.Message() == "Dict: Key of type builtin+map is not hashable"
+			},
+			false,
+		},
+		{
+			"List out of bounds",
+			args{
+				ast: ast.Parsep(tokens.Lexerp(`
+					[1, 2].get(2)
+				`)),
+			},
+			func(r *Runtime) bool { return true },
+			true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -330,3 +779,299 @@ func TestRun_Abstract(t *testing.T) {
 		})
 	}
 }
+
+func TestRuntime_Builtins(t *testing.T) {
+	program := ast.Parsep(tokens.Lexerp(`
+	a = answer()
+	b = isNil(print)
+	`))
+	answer := func(n int) Function {
+		return func(_ *Runtime, _ []Value) (Value, *Error) {
+			return Int(n), nil
+		}
+	}
+	first := New("test.go")
+	first.RegisterBuiltin("answer", answer(1))
+	second := New("test.go")
+	second.RegisterBuiltin("answer", answer(2))
+	second.RemoveBuiltin("print")
+	for i, r := range []*Runtime{first, second} {
+		if _, err := r.Run(program); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if a := r.GetVar("a"); a != Int(i+1) {
+			t.Errorf("a = %v, want %d", a, i+1)
+		}
+		if b := r.GetVar("b"); b != Bool(i == 1) {
+			t.Errorf("b = %v, want %v", b, i == 1)
+		}
+	}
+	if builtins["answer"] != nil {
+		t.Error("RegisterBuiltin() leaked into the default builtins")
+	}
+}
+
+func TestRuntime_Output(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       string
+		stdin      string
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			"print",
+			`print("Hi %s!\n", "Bob")`,
+			"",
+			"Hi Bob!\n",
+			"",
+		},
+		{
+			"eprint",
+			`eprint("failed: %d", 1)`,
+			"",
+			"",
+			"failed: 1",
+		},
+		{
+			"print list",
+			`print("%v %s", [1, "two", [3]], [])`,
+			"",
+			`[1, "two", [3]] []`,
+			"",
+		},
+		{
+			"read line",
+			`
+			a = readLine()
+			b = readLine()
+			c = readLine()
+			print("%s,%s,%v", a, b, isNil(c))
+			`,
+			"first\r\nsecond",
+			"first,second,true",
+			"",
+		},
+		{
+			"read all",
+			`
+			a = readLine()
+			print(readAll())
+			`,
+			"first\nsecond\nthird",
+			"second\nthird",
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			r := New("test.go")
+			r.Stdout = stdout
+			r.Stderr = stderr
+			r.Stdin = strings.NewReader(tt.stdin)
+			if _, err := r.Run(ast.Parsep(tokens.Lexerp(tt.code))); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if stdout.String() != tt.wantStdout {
+				t.Errorf("Stdout = %q, want %q", stdout.String(), tt.wantStdout)
+			}
+			if stderr.String() != tt.wantStderr {
+				t.Errorf("Stderr = %q, want %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
+
+// readerFunc is a reader of a type which is not comparable.
+type readerFunc func([]byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
+
+func TestRuntime_Stdin(t *testing.T) {
+	r := New("test.go")
+	r.Stdin = readerFunc(strings.NewReader("first\nsecond\n").Read)
+	if _, err := r.Run(ast.Parsep(tokens.Lexerp(`
+		a = readLine()
+		b = readLine()
+	`))); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if r.GetVar("a") != String("first") || r.GetVar("b") != String("second") {
+		t.Errorf("a, b = %v, %v, want first, second", r.GetVar("a"), r.GetVar("b"))
+	}
+	r.Stdin = strings.NewReader("third\n")
+	if _, err := r.Run(ast.Parsep(tokens.Lexerp(`c = readLine()`))); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if r.GetVar("c") != String("third") {
+		t.Errorf("c = %v, want third after replacing Stdin", r.GetVar("c"))
+	}
+}
+
+func TestRuntime_Limits(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		setup   func(*Runtime)
+		ctx     func() context.Context
+		want    func(*Runtime) bool
+		wantErr bool
+	}{
+		{
+			"max steps",
+			`while({ true }, { 1 })`,
+			func(r *Runtime) { r.MaxSteps = 1000 },
+			context.Background,
+			nil,
+			true,
+		},
+		{
+			"catchable",
+			`caught = try({ while({ true }, { 1 }) })`,
+			func(r *Runtime) { r.MaxSteps = 1000 },
+			context.Background,
+			func(r *Runtime) bool {
+				_, ok := r.GetVar("caught").(*Error)
+				return ok
+			},
+			false,
+		},
+		{
+			"max depth",
+			`
+			f = () { f() }
+			f()
+			`,
+			func(r *Runtime) { r.MaxDepth = 50 },
+			context.Background,
+			nil,
+			true,
+		},
+		{
+			"stack overflow",
+			`
+			f = (n) { f(n + 1) }
+			err = try({ f(0) })
+			`,
+			func(r *Runtime) {},
+			context.Background,
+			func(r *Runtime) bool {
+				err, ok := r.GetVar("err").(*Error)
+				return ok && strings.HasPrefix(err.Error(), "Stack overflow")
+			},
+			false,
+		},
+		{
+			"timeout",
+			`while({ true }, { 1 })`,
+			func(r *Runtime) { r.Timeout = 10 * time.Millisecond },
+			context.Background,
+			nil,
+			true,
+		},
+		{
+			"cancelled",
+			`a = 1`,
+			func(r *Runtime) {},
+			func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			nil,
+			true,
+		},
+		{
+			"within limits",
+			`
+			i = 0
+			while({ i < 10 }, { i = i + 1 })
+			`,
+			func(r *Runtime) { r.MaxSteps = 1000 },
+			context.Background,
+			func(r *Runtime) bool { return r.GetVar("i") == Int(10) },
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New("test.go")
+			tt.setup(r)
+			_, err := r.RunContext(tt.ctx(), ast.Parsep(tokens.Lexerp(tt.code)))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RunContext() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.want != nil && !tt.want(r) {
+				t.Error("Condition failed")
+			}
+		})
+	}
+}
+
+func TestRuntime_Panics(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+	}{
+		{
+			"native type assertion",
+			`err = try({ 1 == 1.5 })`,
+		},
+		{
+			"nested scopes",
+			`
+			f = () { while({ true }, { if({ true }, { 1 == 1.5 }) }) }
+			err = try(f)
+			`,
+		},
+		{
+			"runtime panic",
+			`
+			n = 0 - 1
+			err = try({ 1 << n })
+			`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New("test.go")
+			_, err := r.Run(ast.Parsep(tokens.Lexerp(tt.code + "\nafter = true")))
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if _, ok := r.GetVar("err").(*Error); !ok {
+				t.Errorf("err = %v, want *Error", r.GetVar("err"))
+			}
+			if r.GetVar("after") != Bool(true) {
+				t.Error("Run() did not continue after the recovered panic")
+			}
+			if r.scope != r.Module || r.budget.depth != 0 {
+				t.Errorf("scope restored = %v, depth = %d, want 0", r.scope == r.Module, r.budget.depth)
+			}
+		})
+	}
+}
+
+func TestError_JSON(t *testing.T) {
+	err := &Error{
+		Err:    fmt.Errorf("failed"),
+		Frames: []Frame{{File: "main.rut", Line: 2, Function: "throw"}},
+		Cause:  &Error{Err: fmt.Errorf("cause")},
+	}
+	got, e := json.Marshal(err)
+	if e != nil {
+		t.Fatal(e)
+	}
+	want := `{"message":"failed","trace":[{"file":"main.rut","line":2,"function":"throw"}],"cause":{"message":"cause","trace":null}}`
+	if string(got) != want {
+		t.Errorf("Marshal() = %s, want %s", got, want)
+	}
+	if summary := err.Summary(); summary != "failed (at throw (main.rut:2))" {
+		t.Errorf("Summary() = %q", summary)
+	}
+}
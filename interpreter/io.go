@@ -0,0 +1,74 @@
+package interpreter
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+)
+
+// input buffers Stdin, it is shared with the runtimes of imported files
+// so no buffered data gets lost between them.
+type input struct {
+	from   io.Reader
+	reader *bufio.Reader
+}
+
+func (R *Runtime) stdin() *bufio.Reader {
+	if R.input.reader == nil || !sameReader(R.input.from, R.Stdin) {
+		R.input.from = R.Stdin
+		R.input.reader = bufio.NewReader(R.Stdin)
+	}
+	return R.input.reader
+}
+
+// sameReader reports whether b is the reader a. Readers are compared
+// by pointer where they have one, as == panics for readers whose type
+// is not comparable, other values of the same type can't be told apart.
+func sameReader(a io.Reader, b io.Reader) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Type() != vb.Type() {
+		return false
+	}
+	switch va.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Chan, reflect.Slice, reflect.UnsafePointer:
+		return va.Pointer() == vb.Pointer()
+	}
+	if va.Type().Comparable() {
+		return a == b
+	}
+	return true
+}
+
+func builtinEprint(r *Runtime, args []Value) (Value, *Error) {
+	return fprint(r, r.Stderr, args)
+}
+
+func builtinReadLine(r *Runtime, args []Value) (Value, *Error) {
+	if len(args) != 0 {
+		return builtinThrow(r, []Value{String("ReadLine: Requires no parameters")})
+	}
+	line, err := r.stdin().ReadString('\n')
+	if err == io.EOF && line == "" {
+		return nil, nil
+	}
+	if err != nil && err != io.EOF {
+		return nil, &Error{Err: err}
+	}
+	return String(strings.TrimRight(line, "\r\n")), nil
+}
+
+func builtinReadAll(r *Runtime, args []Value) (Value, *Error) {
+	if len(args) != 0 {
+		return builtinThrow(r, []Value{String("ReadAll: Requires no parameters")})
+	}
+	content, err := ioutil.ReadAll(r.stdin())
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+	return String(content), nil
+}
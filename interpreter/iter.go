@@ -0,0 +1,220 @@
+package interpreter
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Iterator yields values until next reports it is done.
+// __next__ of an iterator returns the value of stop() once it is done.
+type Iterator struct {
+	next func() (Value, bool, *Error)
+}
+
+// stopIteration is returned by __next__ of a finished iterator.
+type stopIteration struct{}
+
+var iteratorNatives = NativeMap{}
+
+var stopNatives = NativeMap{}
+
+var iterStop = &stopIteration{}
+
+// errStopLoop ends an iteration early.
+var errStopLoop = &Error{}
+
+func (*Iterator) Type() String {
+	return "builtin+iterator"
+}
+
+func (*Iterator) Natives() NativeMap {
+	return iteratorNatives
+}
+
+func (*stopIteration) Type() String {
+	return "builtin+stop"
+}
+
+func (*stopIteration) Natives() NativeMap {
+	return stopNatives
+}
+
+func init() {
+	iteratorNatives[NativeIter] = this
+	iteratorNatives[NativeNext] = Function(func(r *Runtime, v []Value) (Value, *Error) {
+		val, ok, err := v[0].(*Iterator).next()
+		if err != nil || !ok {
+			return iterStop, err
+		}
+		return val, nil
+	})
+
+	listNatives[NativeIter] = Function(func(r *Runtime, v []Value) (Value, *Error) {
+		return v[0].(*List).iterator(), nil
+	})
+	stringNatives[NativeIter] = Function(func(r *Runtime, v []Value) (Value, *Error) {
+		return valuesIterator(stringChars(v[0].(String))), nil
+	})
+	mapNatives[NativeIter] = Function(func(r *Runtime, v []Value) (Value, *Error) {
+		return valuesIterator(sortedKeys(v[0].(Map))), nil
+	})
+	dictNatives[NativeIter] = Function(func(r *Runtime, v []Value) (Value, *Error) {
+		return valuesIterator(sortedKeys(Map(v[0].(Dict)))), nil
+	})
+}
+
+func valuesIterator(values []Value) *Iterator {
+	return (&List{values}).iterator()
+}
+
+func (L *List) iterator() *Iterator {
+	i := 0
+	return &Iterator{func() (Value, bool, *Error) {
+		if i >= len(L.Values) {
+			return nil, false, nil
+		}
+		i++
+		return L.Values[i-1], true, nil
+	}}
+}
+
+func stringChars(s String) []Value {
+	runes := []rune(string(s))
+	chars := make([]Value, len(runes))
+	for i, c := range runes {
+		chars[i] = String(string(c))
+	}
+	return chars
+}
+
+// sortedKeys orders the keys by type and value,
+// so maps are iterated the same way every run.
+func sortedKeys(m Map) []Value {
+	keys := make([]Value, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if typeName(a) != typeName(b) {
+			return typeName(a) < typeName(b)
+		}
+		switch a := a.(type) {
+		case String:
+			return a < b.(String)
+		case Int:
+			return a < b.(Int)
+		case Float:
+			return a < b.(Float)
+		case Bool:
+			return !bool(a) && bool(b.(Bool))
+		}
+		return fmt.Sprint(a) < fmt.Sprint(b)
+	})
+	return keys
+}
+
+// iterator returns the iterator of v, __iter__ may return
+// an iterator or another iterable value.
+func (R *Runtime) iterator(v Value) (Value, *Error) {
+	for i := 0; i < 8; i++ {
+		if R.getNativeField(v, NativeNext) != nil {
+			return v, nil
+		}
+		iter, ok := R.getNativeField(v, NativeIter).(Function)
+		if !ok {
+			break
+		}
+		next, err := R.CallFunction(iter, []Value{v})
+		if err != nil {
+			return nil, err
+		}
+		if hashable(next) && next == v {
+			break
+		}
+		v = next
+	}
+	return builtinThrow(R, []Value{String(fmt.Sprintf("Iter: %s is not iterable", typeName(v)))})
+}
+
+// iterate calls f with every value of the iterable v.
+func (R *Runtime) iterate(v Value, f func(Value) *Error) *Error {
+	iter, err := R.iterator(v)
+	if err != nil {
+		return err
+	}
+	next, ok := R.getNativeField(iter, NativeNext).(Function)
+	if !ok {
+		_, err := builtinThrow(R, []Value{String("Iter: __next__ must be a function")})
+		return err
+	}
+	for {
+		if err := R.step(); err != nil {
+			return err
+		}
+		val, err := R.CallFunction(next, []Value{iter})
+		if err != nil {
+			return err
+		}
+		if val == iterStop {
+			return nil
+		}
+		if err := f(val); err != nil {
+			return err
+		}
+	}
+}
+
+func builtinFor(r *Runtime, args []Value) (Value, *Error) {
+	if len(args) != 2 {
+		return builtinThrow(r, []Value{String("For: Requires exactly 2 parameters")})
+	}
+	defer r.enterLoop()()
+	err := r.iterate(args[0], func(item Value) *Error {
+		_, next, err := r.runLoopBody(args[1], []Value{item})
+		if err == nil && !next {
+			return errStopLoop
+		}
+		return err
+	})
+	if err == errStopLoop {
+		return nil, nil
+	}
+	return nil, err
+}
+
+func builtinStop(r *Runtime, args []Value) (Value, *Error) {
+	return iterStop, nil
+}
+
+// builtinRange counts from start up to the exclusive end:
+//
+//	range(end), range(start, end), range(start, end, step)
+func builtinRange(r *Runtime, args []Value) (Value, *Error) {
+	if len(args) < 1 || len(args) > 3 {
+		return builtinThrow(r, []Value{String("Range: Requires 1 to 3 parameters")})
+	}
+	bounds := []int{0, 0, 1}
+	for i, arg := range args {
+		bound, ok := arg.(Int)
+		if !ok {
+			return builtinThrow(r, []Value{String("Range: Parameters must be integers")})
+		}
+		bounds[i] = int(bound)
+	}
+	if len(args) == 1 {
+		bounds[0], bounds[1] = 0, bounds[0]
+	}
+	start, end, step := bounds[0], bounds[1], bounds[2]
+	if step == 0 {
+		return builtinThrow(r, []Value{String("Range: Step must not be 0")})
+	}
+	i := start
+	return &Iterator{func() (Value, bool, *Error) {
+		if (step > 0 && i >= end) || (step < 0 && i <= end) {
+			return nil, false, nil
+		}
+		i += step
+		return Int(i - step), true, nil
+	}}, nil
+}
@@ -0,0 +1,319 @@
+package interpreter
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// List is a growable sequence of values, lists are shared by reference.
+type List struct {
+	Values []Value
+}
+
+var listNatives = NativeMap{}
+
+var listMembers = map[String]Function{}
+
+func (*List) Type() String {
+	return "builtin+list"
+}
+
+func (*List) Natives() NativeMap {
+	return listNatives
+}
+
+func init() {
+	listNatives[NativeLen] = Function(func(r *Runtime, v []Value) (Value, *Error) {
+		return Int(len(v[0].(*List).Values)), nil
+	})
+	listNatives[NativeBool] = Function(func(r *Runtime, v []Value) (Value, *Error) {
+		return Bool(len(v[0].(*List).Values) != 0), nil
+	})
+	listNatives[NativeStr] = Function(listStr)
+	listNatives[NativeEq] = Function(func(r *Runtime, v []Value) (Value, *Error) {
+		eq, err := r.equal(v[0], v[1])
+		return Bool(eq), err
+	})
+	listNatives[NativeAdd] = Function(func(r *Runtime, v []Value) (Value, *Error) {
+		other, ok := v[1].(*List)
+		if !ok {
+			return builtinThrow(r, []Value{String("List: Can only add lists")})
+		}
+		values := append(append([]Value{}, v[0].(*List).Values...), other.Values...)
+		return &List{values}, nil
+	})
+	listNatives[NativeGetIndex] = Function(listGet)
+	listNatives[NativeSetIndex] = Function(listSet)
+	listNatives[NativeGetMember] = Function(func(r *Runtime, v []Value) (Value, *Error) {
+		str, ok := v[1].(String)
+		if !ok {
+			return nil, nil
+		}
+		if member, ok := listMembers[str]; ok {
+			return member, nil
+		}
+		return nil, nil
+	})
+
+	listMembers["len"] = listNatives[NativeLen].(Function)
+	listMembers["get"] = listGet
+	listMembers["set"] = listSet
+	listMembers["push"] = listPush
+	listMembers["pop"] = listPop
+	listMembers["insert"] = listInsert
+	listMembers["remove"] = listRemove
+	listMembers["slice"] = listSlice
+	listMembers["index"] = listIndex
+	listMembers["contains"] = listContains
+	listMembers["reverse"] = listReverse
+	listMembers["sort"] = listSort
+}
+
+func listStr(r *Runtime, v []Value) (Value, *Error) {
+	values := v[0].(*List).Values
+	parts := make([]string, len(values))
+	for i, value := range values {
+		if str, ok := value.(String); ok {
+			parts[i] = strconv.Quote(string(str))
+			continue
+		}
+		if value == nil {
+			parts[i] = "nil"
+			continue
+		}
+		str, err := r.Str(value)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = str
+	}
+	return String("[" + strings.Join(parts, ", ") + "]"), nil
+}
+
+// indexArg resolves an index into a sequence of length,
+// negative indexes count from the end.
+func indexArg(prefix string, v Value, length int) (int, *Error) {
+	index, ok := v.(Int)
+	if !ok {
+		return 0, &Error{Err: fmt.Errorf("%s: Index must be an integer", prefix)}
+	}
+	i := int(index)
+	if i < 0 {
+		i += length
+	}
+	if i < 0 || i >= length {
+		return 0, &Error{Err: fmt.Errorf("%s: Index %d out of bounds for length %d", prefix, index, length)}
+	}
+	return i, nil
+}
+
+func listGet(r *Runtime, v []Value) (Value, *Error) {
+	if len(v) != 2 {
+		return builtinThrow(r, []Value{String("List: Get requires exactly 1 parameter")})
+	}
+	list := v[0].(*List)
+	i, err := indexArg("List", v[1], len(list.Values))
+	if err != nil {
+		return builtinThrow(r, []Value{String(err.Message())})
+	}
+	return list.Values[i], nil
+}
+
+func listSet(r *Runtime, v []Value) (Value, *Error) {
+	if len(v) != 3 {
+		return builtinThrow(r, []Value{String("List: Set requires exactly 2 parameters")})
+	}
+	list := v[0].(*List)
+	i, err := indexArg("List", v[1], len(list.Values))
+	if err != nil {
+		return builtinThrow(r, []Value{String(err.Message())})
+	}
+	list.Values[i] = v[2]
+	return nil, nil
+}
+
+func listPush(r *Runtime, v []Value) (Value, *Error) {
+	list := v[0].(*List)
+	list.Values = append(list.Values, v[1:]...)
+	return Int(len(list.Values)), nil
+}
+
+func listPop(r *Runtime, v []Value) (Value, *Error) {
+	list := v[0].(*List)
+	if len(list.Values) == 0 {
+		return builtinThrow(r, []Value{String("List: Pop from empty list")})
+	}
+	last := list.Values[len(list.Values)-1]
+	list.Values[len(list.Values)-1] = nil
+	list.Values = list.Values[:len(list.Values)-1]
+	return last, nil
+}
+
+func listInsert(r *Runtime, v []Value) (Value, *Error) {
+	if len(v) != 3 {
+		return builtinThrow(r, []Value{String("List: Insert requires exactly 2 parameters")})
+	}
+	list := v[0].(*List)
+	i, err := indexArg("List", v[1], len(list.Values)+1)
+	if err != nil {
+		return builtinThrow(r, []Value{String(err.Message())})
+	}
+	list.Values = append(list.Values, nil)
+	copy(list.Values[i+1:], list.Values[i:])
+	list.Values[i] = v[2]
+	return nil, nil
+}
+
+func listRemove(r *Runtime, v []Value) (Value, *Error) {
+	if len(v) != 2 {
+		return builtinThrow(r, []Value{String("List: Remove requires exactly 1 parameter")})
+	}
+	list := v[0].(*List)
+	i, err := indexArg("List", v[1], len(list.Values))
+	if err != nil {
+		return builtinThrow(r, []Value{String(err.Message())})
+	}
+	removed := list.Values[i]
+	copy(list.Values[i:], list.Values[i+1:])
+	list.Values[len(list.Values)-1] = nil
+	list.Values = list.Values[:len(list.Values)-1]
+	return removed, nil
+}
+
+// listSlice copies the values from start up to the exclusive end,
+// which defaults to the length of the list.
+func listSlice(r *Runtime, v []Value) (Value, *Error) {
+	if len(v) != 2 && len(v) != 3 {
+		return builtinThrow(r, []Value{String("List: Slice requires 1 or 2 parameters")})
+	}
+	list := v[0].(*List)
+	bounds := []int{0, len(list.Values)}
+	for i, arg := range v[1:] {
+		bound, ok := arg.(Int)
+		if !ok {
+			return builtinThrow(r, []Value{String("List: Slice requires integer bounds")})
+		}
+		bounds[i] = int(bound)
+		if bounds[i] < 0 {
+			bounds[i] += len(list.Values)
+		}
+	}
+	start, end := bounds[0], bounds[1]
+	if start < 0 || end > len(list.Values) || start > end {
+		return builtinThrow(r, []Value{String(fmt.Sprintf("List: Slice [%d:%d] out of bounds for length %d", start, end, len(list.Values)))})
+	}
+	return &List{append([]Value{}, list.Values[start:end]...)}, nil
+}
+
+func listIndex(r *Runtime, v []Value) (Value, *Error) {
+	if len(v) != 2 {
+		return builtinThrow(r, []Value{String("List: Index requires exactly 1 parameter")})
+	}
+	for i, value := range v[0].(*List).Values {
+		eq, err := r.equal(value, v[1])
+		if err != nil {
+			return nil, err
+		}
+		if eq {
+			return Int(i), nil
+		}
+	}
+	return Int(-1), nil
+}
+
+func listContains(r *Runtime, v []Value) (Value, *Error) {
+	index, err := listIndex(r, v)
+	if err != nil {
+		return nil, err
+	}
+	return Bool(index.(Int) >= 0), nil
+}
+
+func listReverse(r *Runtime, v []Value) (Value, *Error) {
+	values := v[0].(*List).Values
+	for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+		values[i], values[j] = values[j], values[i]
+	}
+	return nil, nil
+}
+
+// listSort sorts the list in place by __lt__ of its values,
+// or by the optional function which reports whether a is less than b.
+func listSort(r *Runtime, v []Value) (Value, *Error) {
+	values := v[0].(*List).Values
+	less := func(a, b Value) (Value, *Error) {
+		return r.binary(NativeLt, a, b)
+	}
+	if len(v) > 1 {
+		less = func(a, b Value) (Value, *Error) {
+			return r.invokeValue(v[1], []Value{a, b})
+		}
+	}
+	var err *Error
+	sort.SliceStable(values, func(i, j int) bool {
+		if err != nil {
+			return false
+		}
+		var res Value
+		res, err = less(values[i], values[j])
+		return r.isTrue(res)
+	})
+	return nil, err
+}
+
+// binary applies the operator native of a to a and b.
+func (R *Runtime) binary(native int, a Value, b Value) (Value, *Error) {
+	fn, ok := R.getNativeField(a, native).(Function)
+	if !ok {
+		return builtinThrow(R, []Value{String(fmt.Sprintf("Operator not supported by %s", typeName(a)))})
+	}
+	return R.CallFunction(fn, []Value{a, b})
+}
+
+// equal compares values of the same type by __eq__, lists element wise
+// and other values by identity. Values of different types are never equal.
+func (R *Runtime) equal(a Value, b Value) (bool, *Error) {
+	if a == nil || b == nil {
+		return a == b, nil
+	}
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false, nil
+	}
+	if list, ok := a.(*List); ok {
+		other, ok := b.(*List)
+		if !ok || len(list.Values) != len(other.Values) {
+			return false, nil
+		}
+		for i := range list.Values {
+			eq, err := R.equal(list.Values[i], other.Values[i])
+			if err != nil || !eq {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	if R.getNativeField(a, NativeEq) != nil {
+		res, err := R.binary(NativeEq, a, b)
+		return R.isTrue(res), err
+	}
+	if !reflect.TypeOf(a).Comparable() {
+		return false, nil
+	}
+	return a == b, nil
+}
+
+// isTrue reports whether v is the boolean true.
+func (R *Runtime) isTrue(v Value) bool {
+	b, ok := v.(Bool)
+	return ok && bool(b)
+}
+
+func typeName(v Value) String {
+	if v == nil {
+		return "nil"
+	}
+	return v.Type()
+}
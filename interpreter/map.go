@@ -1,5 +1,7 @@
 package interpreter
 
+import "reflect"
+
 var mapNatives = NativeMap{}
 
 func (Map) Type() String {
@@ -7,6 +9,8 @@ func (Map) Type() String {
 }
 
 func init() {
+	mapNatives[NativeGetIndex] = Function(mapGet)
+	mapNatives[NativeSetIndex] = Function(mapSet)
 	mapNatives[NativeGetMember] = Function(func(r *Runtime, v []Value) (Value, *Error) {
 		str, ok := v[1].(String)
 		if !ok {
@@ -21,6 +25,12 @@ func init() {
 		if str == "has" {
 			return Function(mapHas), nil
 		}
+		if str == "keys" {
+			return Function(mapKeys), nil
+		}
+		if str == "values" {
+			return Function(mapValues), nil
+		}
 		return nil, nil
 	})
 }
@@ -29,10 +39,18 @@ func (Map) Natives() NativeMap {
 	return mapNatives
 }
 
+// hashable reports whether v can be used as key of a map.
+func hashable(v Value) bool {
+	return v == nil || reflect.TypeOf(v).Comparable()
+}
+
 func mapGet(r *Runtime, v []Value) (Value, *Error) {
 	if len(v) != 2 {
 		return builtinThrow(r, []Value{String("Map: Get Requires exactly 1 parameter")})
 	}
+	if !hashable(v[1]) {
+		return builtinThrow(r, []Value{String("Map: Key of type " + typeName(v[1]) + " is not hashable")})
+	}
 	return v[0].(Map)[v[1]], nil
 }
 
@@ -40,6 +58,9 @@ func mapSet(r *Runtime, v []Value) (Value, *Error) {
 	if len(v) != 3 {
 		return builtinThrow(r, []Value{String("Map: Set Requires exactly 2 parameters")})
 	}
+	if !hashable(v[1]) {
+		return builtinThrow(r, []Value{String("Map: Key of type " + typeName(v[1]) + " is not hashable")})
+	}
 	v[0].(Map)[v[1]] = v[2]
 	return nil, nil
 }
@@ -48,6 +69,23 @@ func mapHas(r *Runtime, v []Value) (Value, *Error) {
 	if len(v) != 2 {
 		return builtinThrow(r, []Value{String("Map: Has Requires exactly 1 parameter")})
 	}
+	if !hashable(v[1]) {
+		return builtinThrow(r, []Value{String("Map: Key of type " + typeName(v[1]) + " is not hashable")})
+	}
 	_, ok := v[0].(Map)[v[1]]
 	return Bool(ok), nil
 }
+
+// mapKeys lists the keys in the order they are iterated.
+func mapKeys(r *Runtime, v []Value) (Value, *Error) {
+	return &List{sortedKeys(v[0].(Map))}, nil
+}
+
+func mapValues(r *Runtime, v []Value) (Value, *Error) {
+	m := v[0].(Map)
+	keys := sortedKeys(m)
+	for i, key := range keys {
+		keys[i] = m[key]
+	}
+	return &List{keys}, nil
+}
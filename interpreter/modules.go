@@ -0,0 +1,183 @@
+package interpreter
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// modules caches the exports of imported files by absolute path,
+// it is shared by every runtime forked for an import.
+type modules struct {
+	exports map[string]Value
+	loading []string
+}
+
+func newModules() *modules {
+	return &modules{exports: map[string]Value{}}
+}
+
+// cycle returns the import chain from the entry file to file,
+// if file is still loading, or nil.
+func (M *modules) cycle(importer string, file string) []string {
+	chain := M.loading
+	if len(chain) == 0 {
+		chain = []string{importer}
+	}
+	for _, loading := range chain {
+		if loading == file {
+			return append(append([]string{}, chain...), file)
+		}
+	}
+	return nil
+}
+
+// load runs the module file once and caches its exports,
+// failed modules are run again by the next import.
+func (M *modules) load(importer string, file string, run func() (Value, *Error)) (Value, *Error) {
+	if len(M.loading) == 0 {
+		M.loading = append(M.loading, importer)
+		defer func() { M.loading = M.loading[:0] }()
+	}
+	M.loading = append(M.loading, file)
+	defer func() { M.loading = M.loading[:len(M.loading)-1] }()
+	exports, err := run()
+	if err == nil {
+		M.exports[file] = exports
+	}
+	return exports, err
+}
+
+// formatChain joins an import chain with the files
+// relative to the directory of the first one.
+func formatChain(chain []string) string {
+	dir := filepath.Dir(chain[0])
+	names := make([]string, len(chain))
+	for i, file := range chain {
+		names[i] = file
+		if rel, err := filepath.Rel(dir, file); err == nil {
+			names[i] = rel
+		}
+	}
+	return strings.Join(names, " -> ")
+}
+
+// moduleFile is the source of a module, the path is absolute
+// on disk or relative to the root of a mount.
+type moduleFile struct {
+	mount string
+	path  string
+}
+
+// name identifies the module in the cache and in traces.
+func (M moduleFile) name() string {
+	if M.mount == "" {
+		return M.path
+	}
+	return M.mount + "/" + M.path
+}
+
+func (R *Runtime) readModule(module moduleFile) ([]byte, error) {
+	if module.mount == "" {
+		fsys, name := diskFS(module.path)
+		return fs.ReadFile(fsys, name)
+	}
+	return fs.ReadFile(R.Mounts[module.mount], module.path)
+}
+
+// diskFS returns the file system of the root the absolute path
+// file is on and the name of the file inside of it.
+func diskFS(file string) (fs.FS, string) {
+	root := filepath.VolumeName(file) + string(filepath.Separator)
+	name := filepath.ToSlash(strings.TrimPrefix(file, root))
+	if name == "" {
+		name = "."
+	}
+	return os.DirFS(root), name
+}
+
+// resolveImport finds the file of an import. Names starting with a mount
+// are read from it, names starting with ./ or ../ are relative to the
+// importing file and other names are searched next to it and then in Path.
+// The .rut extension may be left out and a directory imports its index.rut.
+func (R *Runtime) resolveImport(name string) (moduleFile, bool) {
+	if filepath.IsAbs(name) {
+		return diskModule(name)
+	}
+	relative := isRelativeImport(name)
+	if mount, rest, ok := R.splitMount(name); ok && !relative {
+		return R.mountModule(mount, rest)
+	}
+	var module moduleFile
+	var ok bool
+	if R.source.mount != "" {
+		module, ok = R.mountModule(R.source.mount, path.Join(path.Dir(R.source.path), filepath.ToSlash(name)))
+	} else {
+		module, ok = diskModule(filepath.Join(filepath.Dir(R.File), name))
+	}
+	if ok || relative {
+		return module, ok
+	}
+	for _, dir := range R.Path {
+		if module, ok := diskModule(filepath.Join(dir, name)); ok {
+			return module, true
+		}
+	}
+	return moduleFile{}, false
+}
+
+func isRelativeImport(name string) bool {
+	name = filepath.ToSlash(name)
+	return name == "." || name == ".." || strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../")
+}
+
+// splitMount splits name into a mount and the path inside of it.
+func (R *Runtime) splitMount(name string) (string, string, bool) {
+	name = filepath.ToSlash(name)
+	mount, rest := name, ""
+	if slash := strings.Index(name, "/"); slash >= 0 {
+		mount, rest = name[:slash], name[slash+1:]
+	}
+	_, ok := R.Mounts[mount]
+	return mount, path.Clean(rest), ok
+}
+
+func diskModule(base string) (moduleFile, bool) {
+	abs, err := filepath.Abs(base)
+	if err != nil {
+		return moduleFile{}, false
+	}
+	fsys, name := diskFS(abs)
+	file, ok := findModule(fsys, name)
+	if !ok {
+		return moduleFile{}, false
+	}
+	root := filepath.VolumeName(abs) + string(filepath.Separator)
+	return moduleFile{path: filepath.Join(root, filepath.FromSlash(file))}, true
+}
+
+func (R *Runtime) mountModule(mount string, base string) (moduleFile, bool) {
+	fsys, ok := R.Mounts[mount]
+	if !ok || base == ".." || strings.HasPrefix(base, "../") {
+		return moduleFile{}, false
+	}
+	file, ok := findModule(fsys, base)
+	return moduleFile{mount, file}, ok
+}
+
+// findModule returns the first file an import of base refers to.
+func findModule(fsys fs.FS, base string) (string, bool) {
+	candidates := []string{base}
+	if path.Ext(base) == "" {
+		candidates = append(candidates, base+".rut")
+	}
+	candidates = append(candidates, path.Join(base, "index.rut"))
+	for _, file := range candidates {
+		if info, err := fs.Stat(fsys, file); err == nil && info.Mode().IsRegular() {
+			return file, true
+		}
+	}
+	return "", false
+}
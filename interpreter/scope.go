@@ -9,6 +9,33 @@ type Value interface {
 
 type Locals = map[string]Value
 
+// Scope holds the variables of a module or a call,
+// lookups continue in the scope it was created in.
 type Scope struct {
 	variables Locals
+	parent    *Scope
+}
+
+func (S *Scope) child() *Scope {
+	scope := NewScope()
+	scope.parent = S
+	return scope
+}
+
+// lookup finds the nearest scope which defines name.
+func (S *Scope) lookup(name string) (*Scope, Value, bool) {
+	for scope := S; scope != nil; scope = scope.parent {
+		if v, ok := scope.variables[name]; ok {
+			return scope, v, true
+		}
+	}
+	return nil, nil, false
+}
+
+// root is the scope of the module S was created in.
+func (S *Scope) root() *Scope {
+	for S.parent != nil {
+		S = S.parent
+	}
+	return S
 }
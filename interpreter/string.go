@@ -7,6 +7,7 @@ var stringNatives = NativeMap{}
 func init() {
 	stringNatives[NativeStr] = this
 	stringNatives[NativeLen] = Function(func(_ *Runtime, v []Value) (Value, *Error) { return Int(len(v[0].(String))), nil })
+	stringNatives[NativeGetIndex] = Function(stringGetIndex)
 	stringNatives[NativeBool] = Function(func(_ *Runtime, v []Value) (Value, *Error) { return Bool(v[0].(String) != ""), nil })
 	stringNatives[NativeGetMember] = Function(func(_ *Runtime, v []Value) (Value, *Error) {
 		if str, ok := v[1].(String); ok && str == "len" {
@@ -14,6 +15,20 @@ func init() {
 		}
 		return nil, nil
 	})
+
+	stringNatives[NativeLt] = stringWrapBinary(func(a, b string) Value { return Bool(a < b) })
+	stringNatives[NativeLe] = stringWrapBinary(func(a, b string) Value { return Bool(a <= b) })
+	stringNatives[NativeGt] = stringWrapBinary(func(a, b string) Value { return Bool(a > b) })
+	stringNatives[NativeGe] = stringWrapBinary(func(a, b string) Value { return Bool(a >= b) })
+}
+
+func stringWrapBinary(f func(a, b string) Value) Function {
+	return func(r *Runtime, v []Value) (Value, *Error) {
+		if b, ok := v[1].(String); ok {
+			return f(string(v[0].(String)), string(b)), nil
+		}
+		return builtinThrow(r, []Value{String("Invalid right hand value")})
+	}
 }
 
 func (String) Type() String {
@@ -26,6 +41,16 @@ func init() {
 	})
 }
 
+// stringGetIndex is the character at a rune index.
+func stringGetIndex(r *Runtime, v []Value) (Value, *Error) {
+	runes := []rune(string(v[0].(String)))
+	i, err := indexArg("String", v[1], len(runes))
+	if err != nil {
+		return builtinThrow(r, []Value{String(err.Message())})
+	}
+	return String(string(runes[i])), nil
+}
+
 func (String) Natives() NativeMap {
 	return stringNatives
 }
@@ -34,9 +34,16 @@ const (
 	NativeLand
 	NativeLsh
 	NativeRsh
+	NativeNeg
+	NativeGetIndex
+	NativeSetIndex
+	NativeIter
+	NativeNext
+	// nativeCount is the number of natives, new ones are added above
+	nativeCount
 )
 
-type NativeMap [NativeRsh + 1]Value
+type NativeMap [nativeCount]Value
 
 const (
 	TypeRun       = String("__run__")
@@ -62,6 +69,11 @@ const (
 	TypeGe        = String("__ge__")
 	TypeLsh       = String("__lsh__")
 	TypeRsh       = String("__rsh__")
+	TypeNeg       = String("__neg__")
+	TypeGetIndex  = String("__getindex__")
+	TypeSetIndex  = String("__setindex__")
+	TypeIter      = String("__iter__")
+	TypeNext      = String("__next__")
 )
 
 var operatorMagicType = map[tokens.Operator]int{}
@@ -87,6 +99,7 @@ func init() {
 	operatorMagicType[tokens.OperatorLand] = NativeLand
 	operatorMagicType[tokens.OperatorLsh] = NativeLsh
 	operatorMagicType[tokens.OperatorRsh] = NativeRsh
+	operatorMagicType[tokens.OperatorNeg] = NativeNeg
 
 	magicFuncNativeMap[TypeRun] = NativeRun
 	magicFuncNativeMap[TypeInit] = NativeInit
@@ -111,10 +124,13 @@ func init() {
 	magicFuncNativeMap[TypeGe] = NativeGe
 	magicFuncNativeMap[TypeLsh] = NativeLsh
 	magicFuncNativeMap[TypeRsh] = NativeRsh
+	magicFuncNativeMap[TypeNeg] = NativeNeg
+	magicFuncNativeMap[TypeGetIndex] = NativeGetIndex
+	magicFuncNativeMap[TypeSetIndex] = NativeSetIndex
+	magicFuncNativeMap[TypeIter] = NativeIter
+	magicFuncNativeMap[TypeNext] = NativeNext
 }
 
-var floatNatives = NativeMap{}
-
 var this = Function(func(_ *Runtime, v []Value) (Value, *Error) {
 	return v[0], nil
 })
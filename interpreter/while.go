@@ -5,14 +5,16 @@ func builtinWhile(r *Runtime, args []Value) (Value, *Error) {
 		return builtinThrow(r, []Value{String("While: Requires exactly 2 parameters")})
 	}
 	statement := r.getNativeField(args[0], NativeRun)
-	r.lowerScope()
-	defer r.raiseScope()
+	defer r.enterLoop()()
 	if statement == nil {
 		return builtinThrow(r, []Value{String("While: arg 1 must be runnable")})
 	}
 	if fn, ok := statement.(Function); ok {
 		var res Value
 		for true {
+			if err := r.step(); err != nil {
+				return nil, err
+			}
 			val, err := fn(r, []Value{args[0]})
 			if err != nil {
 				return nil, err
@@ -33,10 +35,14 @@ func builtinWhile(r *Runtime, args []Value) (Value, *Error) {
 				if !boolVal {
 					break
 				}
-				res, err = builtinRun(r, []Value{args[1]})
+				var next bool
+				res, next, err = r.runLoopBody(args[1], []Value{})
 				if err != nil {
 					return nil, err
 				}
+				if !next {
+					break
+				}
 			}
 		}
 		return res, nil
@@ -1 +1,169 @@
-package rutist
\ No newline at end of file
+package rutist
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/worldOneo/rutist/ast"
+	"github.com/worldOneo/rutist/interpreter"
+	"github.com/worldOneo/rutist/std"
+	"github.com/worldOneo/rutist/tokens"
+)
+
+// EvalName is the file name used for sources which are not read from disk.
+const EvalName = "<eval>"
+
+// Engine embeds a single Rutist runtime into a Go host.
+// Globals set by one evaluation are visible to the following ones.
+type Engine struct {
+	Runtime *interpreter.Runtime
+}
+
+// Program is a parsed source which can be run any number of times.
+type Program struct {
+	file string
+	node ast.Node
+}
+
+// New creates an engine which can import the standard library as std.
+func New() *Engine {
+	runtime := interpreter.New(EvalName)
+	runtime.Mounts["std"] = std.FS
+	return &Engine{runtime}
+}
+
+func Compile(src string, file string) (*Program, error) {
+	lexed, err := tokens.Lexer(src)
+	if syntax, ok := err.(*tokens.SyntaxError); ok {
+		syntax.File = file
+	}
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := ast.Parse(lexed, file)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{file, parsed}, nil
+}
+
+func CompileFile(path string) (*Program, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(string(content), abs)
+}
+
+func (P *Program) File() string {
+	return P.file
+}
+
+func (E *Engine) Compile(src string) (*Program, error) {
+	return Compile(src, EvalName)
+}
+
+func (E *Engine) Run(program *Program) (interpreter.Value, error) {
+	return E.RunContext(context.Background(), program)
+}
+
+// RunContext runs the program until it completes or ctx is done,
+// the limits configured on E.Runtime apply to every run.
+func (E *Engine) RunContext(ctx context.Context, program *Program) (interpreter.Value, error) {
+	file := E.Runtime.File
+	E.Runtime.File = program.file
+	defer func() { E.Runtime.File = file }()
+	val, err := E.Runtime.RunContext(ctx, program.node)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+func (E *Engine) Eval(src string) (interpreter.Value, error) {
+	return E.EvalContext(context.Background(), src)
+}
+
+func (E *Engine) EvalContext(ctx context.Context, src string) (interpreter.Value, error) {
+	program, err := E.Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return E.RunContext(ctx, program)
+}
+
+func (E *Engine) EvalFile(path string) (interpreter.Value, error) {
+	program, err := CompileFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return E.Run(program)
+}
+
+func (E *Engine) Get(name string) interpreter.Value {
+	return E.Runtime.GetVar(name)
+}
+
+// Set defines the global name, Go values are converted with interpreter.ToValue.
+func (E *Engine) Set(name string, value interface{}) error {
+	val, err := interpreter.ToValue(value)
+	if err != nil {
+		return err
+	}
+	E.Runtime.SetVar(name, val)
+	return nil
+}
+
+// Register defines a builtin for this engine,
+// fn may be any Go func accepted by interpreter.ToValue.
+func (E *Engine) Register(name string, fn interface{}) error {
+	val, err := interpreter.ToValue(fn)
+	if err != nil {
+		return err
+	}
+	function, ok := val.(interpreter.Function)
+	if !ok {
+		return fmt.Errorf("rutist: %s is not a function", name)
+	}
+	E.Runtime.RegisterBuiltin(name, function)
+	return nil
+}
+
+// Call invokes the script value name with the given Go arguments
+// and returns its result as a Go value.
+func (E *Engine) Call(name string, args ...interface{}) (interface{}, error) {
+	return E.CallContext(context.Background(), name, args...)
+}
+
+func (E *Engine) CallContext(ctx context.Context, name string, args ...interface{}) (interface{}, error) {
+	fn := E.Runtime.GetVar(name)
+	if fn == nil {
+		return nil, fmt.Errorf("rutist: %s is not defined", name)
+	}
+	values := make([]interpreter.Value, len(args))
+	for i, arg := range args {
+		val, err := interpreter.ToValue(arg)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = val
+	}
+	res, rErr := E.Runtime.InvokeContext(ctx, fn, values)
+	if rErr != nil {
+		return nil, rErr
+	}
+	var out interface{}
+	err := E.Convert(res, &out)
+	return out, err
+}
+
+// Convert stores the value v into the Go value dst points to.
+func (E *Engine) Convert(v interpreter.Value, dst interface{}) error {
+	return interpreter.FromValue(E.Runtime, v, dst)
+}
@@ -0,0 +1,333 @@
+package rutist
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/worldOneo/rutist/interpreter"
+)
+
+func TestEngine_Call(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		fn      string
+		args    []interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			"add",
+			`add = (a, b) { a + b }`,
+			"add",
+			[]interface{}{1, 2},
+			3,
+			false,
+		},
+		{
+			"string result",
+			`greet = (name) { name }`,
+			"greet",
+			[]interface{}{"bob"},
+			"bob",
+			false,
+		},
+		{
+			"throw",
+			`fail = () { throw("failed") }`,
+			"fail",
+			nil,
+			nil,
+			true,
+		},
+		{
+			"undefined",
+			``,
+			"missing",
+			nil,
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New()
+			if _, err := e.Eval(tt.code); err != nil {
+				t.Fatalf("Eval() error = %v", err)
+			}
+			got, err := e.Call(tt.fn, tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Call() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Call() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngine_Program(t *testing.T) {
+	e := New()
+	program, err := e.Compile(`counter = counter + 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Set("counter", 0); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := e.Run(program); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := e.Get("counter"); got != interpreter.Int(3) {
+		t.Errorf("Get() = %v, want 3", got)
+	}
+}
+
+func TestEngine_Reentrant(t *testing.T) {
+	e := New()
+	e.Runtime.MaxDepth = 50
+	calls := 0
+	err := e.Register("recurse", func(n int) (interface{}, error) {
+		calls++
+		return e.Call("f", n+1)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Eval(`f = (n) { recurse(n) }`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Call("f", 0); err == nil || !strings.Contains(err.Error(), "Stack overflow") {
+		t.Errorf("Call() error = %v, want a stack overflow", err)
+	}
+	if calls > 50 {
+		t.Errorf("recurse called %d times, want the depth limit of 50 to apply", calls)
+	}
+
+	e.Runtime.MaxDepth = 0
+	e.Runtime.MaxSteps = 100
+	if _, err := e.Call("f", 0); err == nil || !strings.Contains(err.Error(), "Step limit") {
+		t.Errorf("Call() error = %v, want the step limit", err)
+	}
+}
+
+type user struct {
+	Name    string
+	Age     int `rutist:"age"`
+	Friends []string
+}
+
+func (u *user) Greet(greeting string) string {
+	return greeting + " " + u.Name
+}
+
+func TestEngine_Convert(t *testing.T) {
+	e := New()
+	u := &user{Name: "bob", Age: 20, Friends: []string{"alice"}}
+	if err := e.Set("user", u); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Set("upper", strings.ToUpper); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Set("fail", func() (int, error) { return 0, errors.New("failed") }); err != nil {
+		t.Fatal(err)
+	}
+	_, err := e.Eval(`
+	user.age = user.age + 1
+	greeting = user.Greet(upper("hi"))
+	friend = user.Friends.get(0)
+	user.Friends[0] = user.Friends[0][0]
+	err = try({ fail() })
+	record = Dict()
+	record.Name = "alice"
+	record.age = 30
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Age != 21 {
+		t.Errorf("Age = %d, want 21", u.Age)
+	}
+	var greeting string
+	if err := e.Convert(e.Get("greeting"), &greeting); err != nil || greeting != "HI bob" {
+		t.Errorf("greeting = %q (%v), want %q", greeting, err, "HI bob")
+	}
+	var friend string
+	if err := e.Convert(e.Get("friend"), &friend); err != nil || friend != "alice" {
+		t.Errorf("friend = %q (%v), want %q", friend, err, "alice")
+	}
+	if u.Friends[0] != "a" {
+		t.Errorf("Friends[0] = %q, want %q", u.Friends[0], "a")
+	}
+	var failure error
+	if err := e.Convert(e.Get("err"), &failure); err != nil || failure == nil || !strings.HasPrefix(failure.Error(), "failed") {
+		t.Errorf("err = %v (%v), want failed", failure, err)
+	}
+	var record user
+	if err := e.Convert(e.Get("record"), &record); err != nil || record.Name != "alice" || record.Age != 30 {
+		t.Errorf("record = %+v (%v)", record, err)
+	}
+	var numbers []int
+	if err := e.Convert(&interpreter.List{Values: []interpreter.Value{interpreter.Int(1), interpreter.Int(2)}}, &numbers); err != nil || !reflect.DeepEqual(numbers, []int{1, 2}) {
+		t.Errorf("numbers = %v (%v), want [1 2]", numbers, err)
+	}
+	var age string
+	if err := e.Convert(interpreter.Int(1), &age); err == nil {
+		t.Error("Convert() expected mismatch error")
+	}
+}
+
+func TestEngine_ConvertFunc(t *testing.T) {
+	e := New()
+	if _, err := e.Eval(`double = (a) { a * 2 }`); err != nil {
+		t.Fatal(err)
+	}
+	var double func(int) (int, error)
+	if err := e.Convert(e.Get("double"), &double); err != nil {
+		t.Fatal(err)
+	}
+	got, err := double(21)
+	if err != nil || got != 42 {
+		t.Errorf("double(21) = %d, %v, want 42", got, err)
+	}
+	var unchecked func(int) int
+	if err := e.Convert(e.Get("double"), &unchecked); err == nil {
+		t.Error("Convert() want an error for a func without an error result")
+	}
+}
+
+// writeFiles writes the files to dir, names may contain directories.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, code := range files {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEngine_Import(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"lib.rut":    `loaded()` + "\n" + `module((export) { export("Point", class((def) {})) })`,
+		"other.rut":  `lib = import("lib.rut")` + "\n" + `module((export) { export("lib", lib) })`,
+		"a.rut":      `import("b.rut")`,
+		"b.rut":      `import("a.rut")`,
+		"main.rut":   `lib = import("lib.rut")` + "\n" + `other = import("./other.rut").lib`,
+		"cyclic.rut": `import("a.rut")`,
+	})
+
+	e := New()
+	loaded := 0
+	if err := e.Register("loaded", func() { loaded++ }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.EvalFile(filepath.Join(dir, "main.rut")); err != nil {
+		t.Fatal(err)
+	}
+	lib := e.Get("lib").(interpreter.Dict)
+	other := e.Get("other").(interpreter.Dict)
+	point := interpreter.String("Point")
+	if loaded != 1 || lib[point] != other[point] {
+		t.Errorf("lib.rut loaded %d times, shared class = %v, want once", loaded, lib[point] == other[point])
+	}
+
+	_, err := e.EvalFile(filepath.Join(dir, "cyclic.rut"))
+	if err == nil {
+		t.Fatal("EvalFile() want circular import error")
+	}
+	messages := []string{}
+	for ; err != nil; err = errors.Unwrap(err) {
+		messages = append(messages, err.Error())
+	}
+	if got := strings.Join(messages, "\n"); !strings.Contains(got, "Circular import cyclic.rut -> a.rut -> b.rut -> a.rut") {
+		t.Errorf("EvalFile() error = %s, want the import chain", got)
+	}
+}
+
+func TestEngine_ImportPath(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"pkg/list.rut":        `module((export) { export("name", "list") })`,
+		"pkg/util/index.rut":  `module((export) { export("name", "util") })`,
+		"script/helper.rut":   `module((export) { export("name", "helper") })`,
+		"script/list.rut":     `module((export) { export("name", "local list") })`,
+		"script/main.rut":     `a = import("list").name` + "\n" + `b = import("util").name` + "\n" + `c = import("./helper").name`,
+		"script/relative.rut": `import("./util")`,
+	})
+
+	e := New()
+	e.Runtime.Path = []string{filepath.Join(dir, "pkg")}
+	if _, err := e.EvalFile(filepath.Join(dir, "script", "main.rut")); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"a": "local list", "b": "util", "c": "helper"} {
+		if got := e.Get(name); got != interpreter.String(want) {
+			t.Errorf("%s = %v, want %s", name, got, want)
+		}
+	}
+	if _, err := e.EvalFile(filepath.Join(dir, "script", "relative.rut")); err == nil || !strings.Contains(err.Error(), `Cannot find module "./util"`) {
+		t.Errorf("EvalFile() error = %v, want relative imports to skip the path", err)
+	}
+}
+
+func TestEngine_ImportMount(t *testing.T) {
+	e := New()
+	e.Runtime.Mounts["mem"] = fstest.MapFS{
+		"greet.rut":        {Data: []byte(`module((export) { export("name", import("./pkg").name) })`)},
+		"pkg/index.rut":    {Data: []byte(`module((export) { export("name", import("./name.rut").name) })`)},
+		"pkg/name.rut":     {Data: []byte(`module((export) { export("name", "mem") })`)},
+		"escape/index.rut": {Data: []byte(`import("../../outside")`)},
+	}
+	if _, err := e.Eval(`
+		list = import("std/list")
+		l = list.New()
+		l.push(4)
+		a = l.get(0)
+		b = import("mem/greet").name
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if e.Get("a") != interpreter.Int(4) || e.Get("b") != interpreter.String("mem") {
+		t.Errorf("a = %v, b = %v, want 4 and mem", e.Get("a"), e.Get("b"))
+	}
+	if _, err := e.Eval(`import("mem/escape")`); err == nil {
+		t.Error("Eval() want imports to stay inside of the mount")
+	}
+}
+
+func TestEngine_ModuleAssignment(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.rut": `count = 0` + "\n" +
+			`module((export) {` + "\n" +
+			`	export("inc", () { @@count = count + 1 })` + "\n" +
+			`	export("count", () { count })` + "\n" +
+			`})`,
+		"main.rut": `a = import("a.rut")` + "\n" + `a.inc()` + "\n" + `a.inc()` + "\n" + `got = a.count()`,
+	})
+	e := New()
+	if _, err := e.EvalFile(filepath.Join(dir, "main.rut")); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Get("got"); got != interpreter.Int(2) {
+		t.Errorf("count of a.rut = %v, want 2", got)
+	}
+	if got := e.Get("count"); got != nil {
+		t.Errorf("count of main.rut = %v, want nil", got)
+	}
+}
@@ -0,0 +1,10 @@
+// Package std is the standard library of rutist,
+// it is bundled into the binary and imported as std/name.
+package std
+
+import "embed"
+
+// FS holds the modules of the standard library.
+//
+//go:embed list.rut test.rut
+var FS embed.FS
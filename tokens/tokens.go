@@ -4,17 +4,53 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 type TokenType = uint32
 type Operator = int
 
+// Position is a location in the source code.
+// Offset counts bytes, Line and Column start at 1 and Column counts runes.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// Span is the source range from Start up to the exclusive End.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+// SyntaxError is an error of the lexer or parser at Span.
+// The lexer leaves File empty, the caller knows the file.
+type SyntaxError struct {
+	Code    string
+	Message string
+	File    string
+	Span    Span
+}
+
+func (S *SyntaxError) Error() string {
+	if S.File == "" {
+		return fmt.Sprintf("%s at %d:%d", S.Message, S.Span.Start.Line, S.Span.Start.Column)
+	}
+	return fmt.Sprintf("%s at %s:%d:%d", S.Message, S.File, S.Span.Start.Line, S.Span.Start.Column)
+}
+
 type Token struct {
 	Type       TokenType
 	Content    string
 	ValueInt   int
 	ValueFloat float64
-	Line       int
+	Start      Position
+	End        Position
+}
+
+func (T Token) Span() Span {
+	return Span{T.Start, T.End}
 }
 
 const (
@@ -32,6 +68,10 @@ const (
 	Scoper
 	Dot
 	OperatorType
+	BracketOpen
+	BracketClosed
+	Hash
+	Colon
 )
 
 const (
@@ -53,6 +93,8 @@ const (
 	OperatorGe
 	OperatorLor
 	OperatorLand
+	// OperatorNeg is the unary -, the lexer emits OperatorSub for it
+	OperatorNeg
 )
 
 var operators = map[string]Operator{}
@@ -84,11 +126,15 @@ const commentIntroduction = "//"
 
 type CodeLexer struct {
 	code        []rune
+	positions   []Position
 	words       []Token
 	currentWord int
 }
 
-func (C *CodeLexer) append(word Token) {
+// append adds the token which spans the runes start up to end.
+func (C *CodeLexer) append(word Token, start int, end int) {
+	word.Start = C.positions[start]
+	word.End = C.positions[end]
 	C.words[C.currentWord] = word
 	C.currentWord++
 	if C.currentWord >= len(C.words) {
@@ -98,6 +144,10 @@ func (C *CodeLexer) append(word Token) {
 	}
 }
 
+func (C *CodeLexer) error(code string, message string, start int, end int) error {
+	return &SyntaxError{Code: code, Message: message, Span: Span{C.positions[start], C.positions[end]}}
+}
+
 func Peek(runes []rune, index int) (rune, bool) {
 	if index < len(runes) {
 		return runes[index], true
@@ -114,8 +164,10 @@ func Lexerp(code string) []Token {
 }
 
 func Lexer(code string) ([]Token, error) {
+	runes := []rune(code)
 	parser := CodeLexer{
-		[]rune(code),
+		runes,
+		positions(runes),
 		make([]Token, 64),
 		0,
 	}
@@ -126,9 +178,25 @@ func Lexer(code string) ([]Token, error) {
 	return words[0:parser.currentWord], err
 }
 
+// positions maps every rune index, and the end of the code, to its position.
+func positions(code []rune) []Position {
+	positions := make([]Position, len(code)+1)
+	pos := Position{0, 1, 1}
+	for i, c := range code {
+		positions[i] = pos
+		pos.Offset += utf8.RuneLen(c)
+		pos.Column++
+		if c == '\n' || (c == '\r' && (i+1 >= len(code) || code[i+1] != '\n')) {
+			pos.Line++
+			pos.Column = 1
+		}
+	}
+	positions[len(code)] = pos
+	return positions
+}
+
 func (C *CodeLexer) Lexer() ([]Token, error) {
 	lineComment := false
-	line := 0
 	buff := strings.Builder{}
 	for i := 0; i < len(C.code); i++ {
 		c := C.code[i]
@@ -139,12 +207,8 @@ func (C *CodeLexer) Lexer() ([]Token, error) {
 			}
 			return 0, false
 		}
-		n, peeked := Peek(C.code, i+1)
+		n, _ := Peek(C.code, i+1)
 		if isNewLine(c) {
-			if isNewLine(n) {
-				i++
-			}
-			line++
 			lineComment = false
 			continue
 		}
@@ -161,44 +225,53 @@ func (C *CodeLexer) Lexer() ([]Token, error) {
 		if isSpecialChar(c) {
 			switch c {
 			case '{':
-				C.append(scopeOpenToken(line))
+				C.append(scopeOpenToken(), i, i+1)
 			case '}':
-				C.append(scopeClosedToken(line))
+				C.append(scopeClosedToken(), i, i+1)
 			case '(':
-				C.append(Token{ParenOpen, "(", 0, 0, line})
+				C.append(symbolToken(ParenOpen, "("), i, i+1)
 			case ')':
-				C.append(Token{ParenClosed, ")", 0, 0, line})
+				C.append(symbolToken(ParenClosed, ")"), i, i+1)
+			case '[':
+				C.append(symbolToken(BracketOpen, "["), i, i+1)
+			case ']':
+				C.append(symbolToken(BracketClosed, "]"), i, i+1)
+			case '#':
+				C.append(symbolToken(Hash, "#"), i, i+1)
+			case ':':
+				C.append(symbolToken(Colon, ":"), i, i+1)
 			case ',':
-				C.append(Token{Comma, ",", 0, 0, line})
-			case '+', '-', '/', '*', '%', '=', '>', '<', '~', '!', '|', '&':
+				C.append(symbolToken(Comma, ","), i, i+1)
+			case '+', '-', '/', '*', '%', '=', '>', '<', '~', '!', '|', '&', '^':
 				sign := string(c)
-				if isSpecialChar(n) {
+				if _, ok := operators[sign+string(n)]; ok || isEqual(n) && c != '=' {
 					sign += string(n)
 				}
 				if sign == "=" {
-					C.append(Token{Assignment, "=", 0, 0, line})
+					C.append(symbolToken(Assignment, "="), i, i+1)
 					continue
 				}
 
 				var ok bool
 				var operator Operator
 				if operator, ok = operators[sign]; ok {
-					C.append(Token{OperatorType, sign, operator, 0, line})
+					C.append(operatorToken(sign, operator), i, i+len(sign))
 				} else {
-					return nil, fmt.Errorf("Invalid operator at line %d", line)
+					return nil, C.error("E001", "Invalid operator "+sign, i, i+len(sign))
 				}
 				if ok {
 					i += len(sign) - 1
 				}
 			case '@':
-				C.append(Token{Scoper, "@", 0, 0, line})
+				C.append(symbolToken(Scoper, "@"), i, i+1)
 			case '.':
-				C.append(Token{Dot, ".", 0, 0, line})
+				C.append(symbolToken(Dot, "."), i, i+1)
 			}
 			continue
 		}
 
 		if isAlpha(c) {
+			start := i
 			buff.Reset()
 			var inc bool
 			for isAlpha(c) {
@@ -212,58 +285,56 @@ func (C *CodeLexer) Lexer() ([]Token, error) {
 			val := buff.String()
 			switch val {
 			case "true":
-				C.append(Token{Boolean, "true", 1, 0, line})
+				C.append(Token{Type: Boolean, Content: "true", ValueInt: 1}, start, i+1)
 			case "false":
-				C.append(Token{Boolean, "false", 0, 0, line})
+				C.append(Token{Type: Boolean, Content: "false"}, start, i+1)
 			default:
-				C.append(Token{Identifier, val, 0, 0, line})
+				C.append(identifierToken(val), start, i+1)
 			}
 			continue
 		}
 
 		if isStringBegin(c) {
+			start := i
 			buff.Reset()
 			escaped := false
 			i++
 			for {
-				c := C.code[i]
-				if isStringBegin(c) {
-					break
+				if i >= len(C.code) {
+					return []Token{}, C.error("E002", "Incomplete string", start, i)
 				}
+				c := C.code[i]
 				if escaped {
 					buff.WriteRune(getEscapedCharacter(c))
 					escaped = false
-					i++
-					continue
-				}
-				if isEscapeChar(c) {
+				} else if isEscapeChar(c) {
 					escaped = true
-					i++
-					continue
-				}
-				buff.WriteRune(c)
-				n, peeked = Peek(C.code, i+1)
-				if !peeked {
-					return []Token{}, fmt.Errorf("Incomplete string")
+				} else if isStringBegin(c) {
+					break
+				} else {
+					buff.WriteRune(c)
 				}
 				i++
 			}
-			C.append(Token{String, buff.String(), 0, 0, line})
+			C.append(stringToken(buff.String()), start, i+1)
 			continue
 		}
 
 		if isDigit(c) {
+			start := i
 			buff.Reset()
 			float := false
 			for isDigit(c) || isNumericalSkipChar(c) || c == '.' {
-				if isNumericalSkipChar(c) {
-					continue
-				}
 				if c == '.' {
 					float = true
 				}
-				buff.WriteRune(c)
+				if !isNumericalSkipChar(c) {
+					buff.WriteRune(c)
+				}
 				i++
+				if i >= len(C.code) {
+					break
+				}
 				c = C.code[i]
 			}
 			i--
@@ -271,16 +342,16 @@ func (C *CodeLexer) Lexer() ([]Token, error) {
 			if !float {
 				intVal, err := strconv.Atoi(str)
 				if err != nil {
-					return []Token{}, fmt.Errorf("Unparseble int literal")
+					return []Token{}, C.error("E003", "Unparseble int literal", start, i+1)
 				}
-				C.append(intToken(str, intVal, line))
+				C.append(intToken(str, intVal), start, i+1)
 				continue
 			}
 			floatVal, err := strconv.ParseFloat(str, 64)
 			if err != nil {
-				return []Token{}, fmt.Errorf("Unparseble float literal")
+				return []Token{}, C.error("E003", "Unparseble float literal", start, i+1)
 			}
-			C.append(floatToken(str, floatVal, line))
+			C.append(floatToken(str, floatVal), start, i+1)
 			continue
 		}
 	}
@@ -306,13 +377,15 @@ func isStringBegin(b rune) bool {
 func isSpecialChar(b rune) bool {
 	return b == '{' || b == '}' ||
 		b == '(' || b == ')' ||
+		b == '[' || b == ']' ||
+		b == '#' || b == ':' ||
 		b == ',' || b == '.' ||
 		b == '<' || b == '>' ||
 		b == '~' || b == '!' ||
 		b == '|' || b == '&' ||
 		b == '+' || b == '-' ||
 		b == '*' || b == '/' ||
-		b == '%' ||
+		b == '%' || b == '^' ||
 		isEqual(b) || isScoper(b)
 }
 
@@ -355,26 +428,37 @@ func getEscapedCharacter(b rune) rune {
 	}
 }
 
-func scopeOpenToken(line int) Token {
-	return Token{ScopeOpen, "{", 0, 0, line}
+// The token constructors leave the position empty,
+// the lexer sets it when appending the token.
+
+func symbolToken(t TokenType, content string) Token {
+	return Token{Type: t, Content: content}
+}
+
+func operatorToken(content string, operator Operator) Token {
+	return Token{Type: OperatorType, Content: content, ValueInt: operator}
+}
+
+func scopeOpenToken() Token {
+	return symbolToken(ScopeOpen, "{")
 }
 
-func scopeClosedToken(line int) Token {
-	return Token{ScopeClosed, "}", 0, 0, line}
+func scopeClosedToken() Token {
+	return symbolToken(ScopeClosed, "}")
 }
 
-func intToken(str string, val int, line int) Token {
-	return Token{Integer, str, val, 0, line}
+func intToken(str string, val int) Token {
+	return Token{Type: Integer, Content: str, ValueInt: val}
 }
 
-func floatToken(str string, val float64, line int) Token {
-	return Token{Float, str, 0, val, line}
+func floatToken(str string, val float64) Token {
+	return Token{Type: Float, Content: str, ValueFloat: val}
 }
 
-func stringToken(content string, line int) Token {
-	return Token{String, content, 0, 0, line}
+func stringToken(content string) Token {
+	return symbolToken(String, content)
 }
 
-func identifierToken(id string, line int) Token {
-	return Token{Identifier, id, 0, 0, line}
+func identifierToken(id string) Token {
+	return symbolToken(Identifier, id)
 }
@@ -5,6 +5,22 @@ import (
 	"testing"
 )
 
+// onLine places the tokens on line.
+func onLine(line int, tokens ...Token) []Token {
+	for i := range tokens {
+		tokens[i].Start = Position{Line: line}
+	}
+	return tokens
+}
+
+func join(lines ...[]Token) []Token {
+	tokens := []Token{}
+	for _, line := range lines {
+		tokens = append(tokens, line...)
+	}
+	return tokens
+}
+
 func TestCodeLexer_Lexer(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -17,11 +33,11 @@ func TestCodeLexer_Lexer(t *testing.T) {
 			`scope {
 				print("test")
 			}`,
-			[]Token{
-				identifierToken("scope", 0), scopeOpenToken(0),
-				identifierToken("print", 1), {ParenOpen, "(", 0, 0, 1}, stringToken("test", 1), {ParenClosed, ")", 0, 0, 1},
-				scopeClosedToken(2),
-			},
+			join(
+				onLine(1, identifierToken("scope"), scopeOpenToken()),
+				onLine(2, identifierToken("print"), symbolToken(ParenOpen, "("), stringToken("test"), symbolToken(ParenClosed, ")")),
+				onLine(3, scopeClosedToken()),
+			),
 			false,
 		},
 		{
@@ -29,11 +45,11 @@ func TestCodeLexer_Lexer(t *testing.T) {
 			`@{
 				print(1)
 			}`,
-			[]Token{
-				{Scoper, "@", 0, 0, 0}, scopeOpenToken(0),
-				identifierToken("print", 1), {ParenOpen, "(", 0, 0, 1}, intToken("1", 1, 1), {ParenClosed, ")", 0, 0, 1},
-				scopeClosedToken(2),
-			},
+			join(
+				onLine(1, symbolToken(Scoper, "@"), scopeOpenToken()),
+				onLine(2, identifierToken("print"), symbolToken(ParenOpen, "("), intToken("1", 1), symbolToken(ParenClosed, ")")),
+				onLine(3, scopeClosedToken()),
+			),
 			false,
 		},
 		{
@@ -41,29 +57,65 @@ func TestCodeLexer_Lexer(t *testing.T) {
 			`
 			print("Test\\\n")
 			`,
-			[]Token{
-				identifierToken("print", 1), {ParenOpen, "(", 0, 0, 1}, stringToken("Test\\\n", 1), {ParenClosed, ")", 0, 0, 1},
-			},
+			onLine(2, identifierToken("print"), symbolToken(ParenOpen, "("), stringToken("Test\\\n"), symbolToken(ParenClosed, ")")),
 			false,
 		},
 		{
 			"dot member separator",
 			`var.member("call")`,
-			[]Token{
-				identifierToken("var", 0), {Dot, ".", 0, 0, 0}, identifierToken("member", 0), {ParenOpen, "(", 0, 0, 0}, stringToken("call", 0), {ParenClosed, ")", 0, 0, 0},
-			},
+			onLine(1, identifierToken("var"), symbolToken(Dot, "."), identifierToken("member"), symbolToken(ParenOpen, "("), stringToken("call"), symbolToken(ParenClosed, ")")),
 			false,
 		},
 		{
 			"operators",
 			`a<b c+d c>=d ~a a||b`,
-			[]Token{
-				identifierToken("a", 0), {OperatorType, "<", OperatorLt, 0, 0}, identifierToken("b", 0),
-				identifierToken("c", 0), {OperatorType, "+", OperatorAdd, 0, 0}, identifierToken("d", 0),
-				identifierToken("c", 0), {OperatorType, ">=", OperatorGe, 0, 0}, identifierToken("d", 0),
-				{OperatorType, "~", OperatorNot, 0, 0}, identifierToken("a", 0),
-				identifierToken("a", 0), {OperatorType, "||", OperatorLor, 0, 0}, identifierToken("b", 0),
-			},
+			onLine(1,
+				identifierToken("a"), operatorToken("<", OperatorLt), identifierToken("b"),
+				identifierToken("c"), operatorToken("+", OperatorAdd), identifierToken("d"),
+				identifierToken("c"), operatorToken(">=", OperatorGe), identifierToken("d"),
+				operatorToken("~", OperatorNot), identifierToken("a"),
+				identifierToken("a"), operatorToken("||", OperatorLor), identifierToken("b"),
+			),
+			false,
+		},
+		{
+			"unknown operator",
+			`a=-b*-c!=d`,
+			nil,
+			true,
+		},
+		{
+			"unary operators",
+			`a*-b !!c x^y`,
+			onLine(1,
+				identifierToken("a"), operatorToken("*", OperatorMul), operatorToken("-", OperatorSub), identifierToken("b"),
+				operatorToken("!", OperatorNot), operatorToken("!", OperatorNot), identifierToken("c"),
+				identifierToken("x"), operatorToken("^", OperatorXor), identifierToken("y"),
+			),
+			false,
+		},
+		{
+			"brackets",
+			`a=[1, b]`,
+			onLine(1,
+				identifierToken("a"), symbolToken(Assignment, "="), symbolToken(BracketOpen, "["),
+				intToken("1", 1), symbolToken(Comma, ","), identifierToken("b"), symbolToken(BracketClosed, "]"),
+			),
+			false,
+		},
+		{
+			"map literal",
+			`#{"a": 1}`,
+			onLine(1,
+				symbolToken(Hash, "#"), scopeOpenToken(), stringToken("a"), symbolToken(Colon, ":"),
+				intToken("1", 1), scopeClosedToken(),
+			),
+			false,
+		},
+		{
+			"trailing number",
+			`a = 1_000`,
+			onLine(1, identifierToken("a"), symbolToken(Assignment, "="), intToken("1000", 1000)),
 			false,
 		},
 		{
@@ -80,9 +132,39 @@ func TestCodeLexer_Lexer(t *testing.T) {
 				t.Errorf("WordParser.Parse() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			for i := range got {
+				got[i].Start = Position{Line: got[i].Start.Line} // Only lines are compared here
+				got[i].End = Position{}
+			}
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("WordParser.Parse() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestCodeLexer_Positions(t *testing.T) {
+	got, err := Lexer("a = \"ä\"\r\n\r\n  b.c(12)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Span{
+		{Position{0, 1, 1}, Position{1, 1, 2}},
+		{Position{2, 1, 3}, Position{3, 1, 4}},
+		{Position{4, 1, 5}, Position{8, 1, 8}},
+		{Position{14, 3, 3}, Position{15, 3, 4}},
+		{Position{15, 3, 4}, Position{16, 3, 5}},
+		{Position{16, 3, 5}, Position{17, 3, 6}},
+		{Position{17, 3, 6}, Position{18, 3, 7}},
+		{Position{18, 3, 7}, Position{20, 3, 9}},
+		{Position{20, 3, 9}, Position{21, 3, 10}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Lexer() = %v, want %d tokens", got, len(want))
+	}
+	for i, token := range got {
+		if span := (Span{token.Start, token.End}); span != want[i] {
+			t.Errorf("token %d %q span = %v, want %v", i, token.Content, span, want[i])
+		}
+	}
+}